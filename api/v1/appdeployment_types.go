@@ -42,6 +42,37 @@ type AppDeploymentSpec struct {
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 	// Template is the pod template specification
 	Template PodTemplateSpec `json:"template,omitempty"`
+	// Adopt lets the AppDeployment take over an existing Deployment with the same name that has no
+	// controller, back-filling unset spec fields from it, instead of failing on the name collision
+	Adopt bool `json:"adopt,omitempty"`
+	// DriftPolicy decides what happens when the generated resources are edited out of band:
+	// "report" only flags the drift, "revert" re-applies the desired state
+	// +kubebuilder:validation:Enum=report;revert
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// PreDeleteHook is an optional container run to completion before the owned resources are torn down
+	PreDeleteHook *PreDeleteHook `json:"preDeleteHook,omitempty"`
+}
+
+// DriftPolicy describes how the controller reacts to drift in the resources it generates
+type DriftPolicy string
+
+const (
+	// DriftPolicyReport records drift in the status without touching the live resources
+	DriftPolicyReport DriftPolicy = "report"
+	// DriftPolicyRevert overwrites drifted fields with the desired state
+	DriftPolicyRevert DriftPolicy = "revert"
+)
+
+// PreDeleteHook describes a one-off container (e.g. drain traffic, deregister from discovery)
+// that runs as a Job when the AppDeployment is deleted, before anything else is removed.
+type PreDeleteHook struct {
+	// Image is the container image that runs the hook
+	Image string `json:"image"`
+	// Command overrides the entrypoint of the hook image
+	Command []string `json:"command,omitempty"`
+	// TimeoutSeconds bounds how long teardown waits for the hook before carrying on (defaults to 300)
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
 }
 
 // AppDeploymentStatus defines the observed state of AppDeployment.
@@ -49,16 +80,39 @@ type AppDeploymentStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// State represents the current state of the AppDeployment (Running, Pending, Failed)
+	// State represents the current state of the AppDeployment (Running, Pending, Failed, Terminating)
 	State string `json:"state,omitempty"`
 	// Message provides additional information about the current state
 	Message string `json:"message,omitempty"`
 	// AvailableReplicas represents the number of replicas that are available
 	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// ConfigHashes holds the content hash of each referenced ConfigMap and Secret that was last rolled out,
+	// keyed by "configmap/<name>" or "secret/<name>"
+	ConfigHashes map[string]string `json:"configHashes,omitempty"`
+	// LastRolloutTrigger describes what caused the most recent rolling restart
+	LastRolloutTrigger string `json:"lastRolloutTrigger,omitempty"`
+	// LastRolloutTime is when the most recent rolling restart was triggered
+	LastRolloutTime *metav1.Time `json:"lastRolloutTime,omitempty"`
+	// ObservedGeneration is the AppDeployment generation last applied to the generated resources
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Drift lists the fields of the generated resources that were changed outside the controller
+	Drift []FieldDiff `json:"drift,omitempty"`
 	// Conditions represents the latest available observations of AppDeployment's current state
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// FieldDiff describes a field whose live value differs from the value the AppDeployment produces
+type FieldDiff struct {
+	// Resource identifies the generated object, e.g. Deployment/my-app
+	Resource string `json:"resource"`
+	// Path is the field path within the resource, e.g. spec.template.spec.containers[web].image
+	Path string `json:"path"`
+	// Desired is the value the AppDeployment produces
+	Desired string `json:"desired,omitempty"`
+	// Live is the value found in the cluster
+	Live string `json:"live,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
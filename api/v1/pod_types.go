@@ -54,6 +54,10 @@ type Container struct {
 
 	// List of ports to expose from the container.
 	Ports []ContainerPort `json:"ports,omitempty"`
+
+	// List of sources to populate environment variables in the container.
+	// A change to the content of any referenced ConfigMap or Secret triggers a rolling restart.
+	EnvFrom []EnvFromSource `json:"envFrom,omitempty"`
 }
 
 // ContainerPort represents a network port in a single container.
@@ -61,3 +65,19 @@ type ContainerPort struct {
 	// Number of port to expose on the pod's IP address.
 	ContainerPort int32 `json:"containerPort"`
 }
+
+// EnvFromSource represents the source of a set of environment variables.
+// Exactly one of ConfigMapRef or SecretRef should be set.
+type EnvFromSource struct {
+	// The ConfigMap to select from.
+	ConfigMapRef *LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// The Secret to select from.
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// LocalObjectReference contains enough information to locate the referenced object inside the same namespace.
+type LocalObjectReference struct {
+	// Name of the referent.
+	Name string `json:"name"`
+}
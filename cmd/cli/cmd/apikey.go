@@ -0,0 +1,137 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyNamespaces []string
+var apiKeyVerbs []string
+var apiKeyExpiresIn time.Duration
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys",
+	Long: `Create, list and revoke long-lived API keys for non-interactive use such as CI pipelines.
+Export a key as GO_ASSESSMENT_TOKEN to use it instead of the stored login.`,
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create an API key",
+	Long:  `Create an API key acting as you, limited to the given namespaces and verbs. The key is only shown once.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		req := client.CreateAPIKeyRequest{
+			Name:       args[0],
+			Namespaces: apiKeyNamespaces,
+			Verbs:      apiKeyVerbs,
+		}
+		if len(req.Namespaces) == 0 {
+			req.Namespaces = []string{namespace}
+		}
+		if apiKeyExpiresIn > 0 {
+			expiresAt := time.Now().Add(apiKeyExpiresIn)
+			req.ExpiresAt = &expiresAt
+		}
+
+		key, err := c.CreateAPIKey(req)
+		if err != nil {
+			fmt.Printf("❌ Failed to create API key: %v\n", err)
+			return
+		}
+
+		fmt.Printf("🔑 API key %s (%s) created\n", key.Name, key.ID)
+		fmt.Printf("   Namespaces: %s, verbs: %s\n", strings.Join(key.Namespaces, ", "), strings.Join(key.Verbs, ", "))
+		fmt.Printf("\n%s\n\n", key.Key)
+		fmt.Println("Store it now, it will not be shown again. Use it with: export GO_ASSESSMENT_TOKEN=<key>")
+	},
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your API keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		keys, err := c.ListAPIKeys()
+		if err != nil {
+			fmt.Printf("❌ Failed to list API keys: %v\n", err)
+			return
+		}
+
+		if len(keys) == 0 {
+			fmt.Println("No API keys")
+			return
+		}
+		for _, key := range keys {
+			lastUsed := "never used"
+			if key.LastUsedAt != nil {
+				lastUsed = "last used " + key.LastUsedAt.Local().Format(time.RFC1123)
+			}
+			fmt.Printf("🔑 %s  %s  namespaces=%s verbs=%s  %s\n", key.ID, key.Name,
+				strings.Join(key.Namespaces, ","), strings.Join(key.Verbs, ","), lastUsed)
+		}
+	},
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke ID",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		if err := c.RevokeAPIKey(args[0]); err != nil {
+			fmt.Printf("❌ Failed to revoke API key: %v\n", err)
+			return
+		}
+
+		fmt.Printf("✅ API key %s revoked\n", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apiKeyCmd)
+	apiKeyCmd.AddCommand(apiKeyCreateCmd, apiKeyListCmd, apiKeyRevokeCmd)
+
+	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyNamespaces, "namespaces", nil,
+		"Namespaces the key may act in, \"*\" for all (default: the --namespace flag)")
+	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyVerbs, "verbs", []string{"get", "list"},
+		"Verbs the key may perform: get, list, watch, create, update, patch, delete, logs")
+	apiKeyCreateCmd.Flags().DurationVar(&apiKeyExpiresIn, "expires-in", 0, "Lifetime of the key, e.g. 2160h (default: no expiry)")
+}
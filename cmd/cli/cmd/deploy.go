@@ -18,8 +18,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
 
-	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
 	"github.com/espinozasenior/go-assesstment.git/pkg/client"
 	"github.com/spf13/cobra"
 )
@@ -30,22 +31,32 @@ var (
 	memoryLimit string
 	minReplicas int32
 	maxReplicas int32
+	configMaps  []string
+	secrets     []string
+
+	deployWait    bool
+	deployTimeout time.Duration
+	deployDryRun  bool
+
+	deployIdempotencyKey string
 )
 
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy an application",
-	Long:  `Deploy an application using the provided parameters.`,
+	Long: `Deploy an application using the provided parameters.
+Deploying an existing application updates it. With --idempotency-key, a retry of the same
+deploy (for instance by a CI job that timed out) is not applied twice.
+With --wait, follow the rollout until the application is Running; the command exits
+non-zero if it ends Failed or --timeout passes. With --dry-run, the request is validated by
+the cluster and the changes it would make are shown, but nothing is created.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		token, err := auth.GetToken()
+		c, err := newClient()
 		if err != nil {
 			fmt.Printf("❌ %v\n", err)
 			return
 		}
 
-		// Create a new client with the API server URL and token
-		c := client.NewClient("http://localhost:8080", token)
-
 		// Create the deploy request
 		req := client.DeployRequest{
 			Image:       image,
@@ -53,20 +64,62 @@ var deployCmd = &cobra.Command{
 			MemoryLimit: memoryLimit,
 			MinReplicas: minReplicas,
 			MaxReplicas: maxReplicas,
+			ConfigMaps:  configMaps,
+			Secrets:     secrets,
+		}
+
+		if deployDryRun {
+			dryRunDeploy(c, req)
+			return
 		}
 
 		fmt.Printf("📦 Deploying %s...\n", name)
 
 		// Send the deploy request
-		if err := c.Deploy(req); err != nil {
+		resp, err := c.DeployWithOptions(req, client.DeployOptions{IdempotencyKey: deployIdempotencyKey})
+		if err != nil {
 			fmt.Printf("❌ Deployment failed: %v\n", err)
 			return
 		}
 
-		fmt.Println("✅ Deployment CRD created.")
+		switch {
+		case resp.Replayed:
+			fmt.Printf("✅ %s (already applied with this idempotency key).\n", resp.Message)
+		case resp.Created:
+			fmt.Println("✅ Deployment CRD created.")
+		default:
+			fmt.Printf("✅ %s.\n", resp.Message)
+		}
+		if !deployWait {
+			return
+		}
+
+		fmt.Printf("⏳ Waiting for %s to roll out...\n", name)
+		if err := followRollout(c, name, deployTimeout); err != nil {
+			fmt.Printf("❌ %s did not roll out: %v\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ %s is running\n", name)
 	},
 }
 
+// dryRunDeploy validates a deploy request server-side and shows what it would change
+func dryRunDeploy(c *client.Client, req client.DeployRequest) {
+	fmt.Printf("🔍 Dry run of deploying %s...\n", req.Name)
+	if err := c.DeployDryRun(req); err != nil {
+		fmt.Printf("❌ Deployment would fail: %v\n", err)
+		os.Exit(1)
+	}
+
+	diff, err := c.DiffApp(req)
+	if err != nil {
+		fmt.Printf("❌ Diff failed: %v\n", err)
+		os.Exit(1)
+	}
+	printDiff(diff)
+	fmt.Println("✅ Dry run succeeded, nothing was changed.")
+}
+
 func init() {
 	rootCmd.AddCommand(deployCmd)
 
@@ -75,6 +128,12 @@ func init() {
 	deployCmd.Flags().StringVar(&memoryLimit, "memoryLimit", "", "Memory limit for the deployment (e.g., 512Mi)")
 	deployCmd.Flags().Int32Var(&minReplicas, "minReplicas", 1, "Minimum number of replicas")
 	deployCmd.Flags().Int32Var(&maxReplicas, "maxReplicas", 3, "Maximum number of replicas")
+	deployCmd.Flags().StringSliceVar(&configMaps, "configMap", nil, "ConfigMap to expose as environment variables (repeatable)")
+	deployCmd.Flags().StringSliceVar(&secrets, "secret", nil, "Secret to expose as environment variables (repeatable)")
+	deployCmd.Flags().BoolVar(&deployWait, "wait", false, "Wait until the deployment is running")
+	deployCmd.Flags().DurationVar(&deployTimeout, "timeout", 5*time.Minute, "How long --wait waits before giving up")
+	deployCmd.Flags().BoolVar(&deployDryRun, "dry-run", false, "Validate the deployment and show what it would change without creating it")
+	deployCmd.Flags().StringVar(&deployIdempotencyKey, "idempotency-key", "", "Key identifying this deploy, so that retries with the same key are applied once")
 
 	if err := deployCmd.MarkFlagRequired("image"); err != nil {
 		fmt.Printf("Error marking image flag as required: %v\n", err)
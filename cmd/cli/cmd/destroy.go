@@ -18,34 +18,46 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
 
-	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
-	"github.com/espinozasenior/go-assesstment.git/pkg/client"
 	"github.com/spf13/cobra"
 )
 
-var destroyName string
+var (
+	destroyName    string
+	destroyWait    bool
+	destroyTimeout time.Duration
+)
 
 var destroyCmd = &cobra.Command{
 	Use:   "destroy",
 	Short: "Destroy a deployment",
 	Long:  `Delete a deployment from the system.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		token, err := auth.GetToken()
+		c, err := newClient()
 		if err != nil {
 			fmt.Printf("❌ %v\n", err)
 			return
 		}
 
-		// Create a new client with the API server URL and token
-		c := client.NewClient("http://localhost:8080", token)
-
 		// Destroy the deployment
 		if err := c.DestroyDeployment(destroyName); err != nil {
 			fmt.Printf("❌ Failed to destroy deployment: %v\n", err)
 			return
 		}
 
+		if !destroyWait {
+			fmt.Printf("❌ %s destroyed\n", destroyName)
+			return
+		}
+
+		fmt.Printf("⏳ Waiting for %s to be torn down...\n", destroyName)
+		if err := waitForDeletion(c, destroyName, destroyTimeout); err != nil {
+			fmt.Printf("❌ %s was not torn down: %v\n", destroyName, err)
+			os.Exit(1)
+		}
+
 		fmt.Printf("❌ %s destroyed\n", destroyName)
 	},
 }
@@ -54,6 +66,8 @@ func init() {
 	rootCmd.AddCommand(destroyCmd)
 
 	destroyCmd.Flags().StringVar(&destroyName, "name", "", "Name of the deployment to destroy")
+	destroyCmd.Flags().BoolVar(&destroyWait, "wait", false, "Wait until the deployment has been fully torn down")
+	destroyCmd.Flags().DurationVar(&destroyTimeout, "timeout", 5*time.Minute, "How long --wait waits before giving up")
 	if err := destroyCmd.MarkFlagRequired("name"); err != nil {
 		fmt.Printf("Error marking name flag as required: %v\n", err)
 	}
@@ -0,0 +1,94 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var diffRequest client.DeployRequest
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what a deploy would change",
+	Long: `Compare the given deployment parameters against the live AppDeployment and its
+Deployment, without changing anything. Fields are listed with their live and new values.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		diff, err := c.DiffApp(diffRequest)
+		if err != nil {
+			fmt.Printf("❌ Diff failed: %v\n", err)
+			os.Exit(1)
+		}
+		printDiff(diff)
+	},
+}
+
+// printDiff lists the changes of a diff grouped by resource: "+" for fields that would be
+// set, "-" for fields that would be cleared and "~" for fields that would change
+func printDiff(diff *client.DiffResponse) {
+	if !diff.Exists {
+		fmt.Printf("✨ %s does not exist yet and would be created\n", diff.Name)
+	}
+	if len(diff.Changes) == 0 {
+		fmt.Printf("No changes for %s\n", diff.Name)
+		return
+	}
+
+	resource := ""
+	for _, change := range diff.Changes {
+		if change.Resource != resource {
+			resource = change.Resource
+			fmt.Println(resource)
+		}
+		switch {
+		case change.Live == "":
+			fmt.Printf("  + %s: %s\n", change.Path, change.Desired)
+		case change.Desired == "":
+			fmt.Printf("  - %s: %s\n", change.Path, change.Live)
+		default:
+			fmt.Printf("  ~ %s: %s → %s\n", change.Path, change.Live, change.Desired)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&diffRequest.Image, "image", "", "Container image to deploy")
+	diffCmd.Flags().StringVar(&diffRequest.Name, "name", "", "Name of the deployment")
+	diffCmd.Flags().StringVar(&diffRequest.MemoryLimit, "memoryLimit", "", "Memory limit for the deployment (e.g., 512Mi)")
+	diffCmd.Flags().Int32Var(&diffRequest.MinReplicas, "minReplicas", 1, "Minimum number of replicas")
+	diffCmd.Flags().Int32Var(&diffRequest.MaxReplicas, "maxReplicas", 3, "Maximum number of replicas")
+	diffCmd.Flags().StringSliceVar(&diffRequest.ConfigMaps, "configMap", nil, "ConfigMap to expose as environment variables (repeatable)")
+	diffCmd.Flags().StringSliceVar(&diffRequest.Secrets, "secret", nil, "Secret to expose as environment variables (repeatable)")
+
+	for _, flag := range []string{"image", "name", "memoryLimit"} {
+		if err := diffCmd.MarkFlagRequired(flag); err != nil {
+			fmt.Printf("Error marking %s flag as required: %v\n", flag, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var eventsName string
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the events of a deployment",
+	Long: `Show the Kubernetes events of a deployment, its Deployment, ReplicaSets and pods,
+merged in chronological order.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		events, err := c.GetAppEvents(eventsName)
+		if errors.Is(err, client.ErrNotFound) {
+			fmt.Printf("❌ Deployment %s not found\n", eventsName)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to get events: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(events) == 0 {
+			fmt.Printf("No events for %s\n", eventsName)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+		for _, event := range events {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", age(event.Time), event.Type, event.Reason, event.Object, event.Message)
+		}
+		_ = w.Flush()
+	},
+}
+
+// age renders how long ago t was, in the style of kubectl
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case t.IsZero():
+		return "<unknown>"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().StringVar(&eventsName, "name", "", "Name of the deployment to show the events of")
+	if err := eventsCmd.MarkFlagRequired("name"); err != nil {
+		fmt.Printf("Error marking name flag as required: %v\n", err)
+	}
+}
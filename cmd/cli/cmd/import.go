@@ -0,0 +1,62 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importName       string
+	importDeployment string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Adopt an existing Deployment",
+	Long:  `Bring an existing Kubernetes Deployment under AppDeployment management.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		fmt.Printf("📥 Importing deployment %s...\n", importDeployment)
+
+		// Send the import request
+		if err := c.Import(client.ImportRequest{Name: importName, Deployment: importDeployment}); err != nil {
+			fmt.Printf("❌ Import failed: %v\n", err)
+			return
+		}
+
+		fmt.Println("✅ AppDeployment created, the controller will take over the deployment.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importDeployment, "deployment", "", "Name of the existing Deployment to adopt")
+	importCmd.Flags().StringVar(&importName, "name", "", "Name of the AppDeployment to create (defaults to the Deployment name)")
+	if err := importCmd.MarkFlagRequired("deployment"); err != nil {
+		fmt.Printf("Error marking deployment flag as required: %v\n", err)
+	}
+}
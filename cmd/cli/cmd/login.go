@@ -20,20 +20,54 @@ import (
 	"fmt"
 
 	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
+	"github.com/espinozasenior/go-assesstment.git/pkg/oidc"
 	"github.com/spf13/cobra"
 )
 
 var (
-	username string
-	password string
+	username     string
+	password     string
+	oidcIssuer   string
+	oidcClientID string
 )
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate with the API",
-	Long:  `Login to the API using your username and password.`,
+	Long: `Login to the API using your username and password. The server returns an
+access token and a refresh token, both stored in ~/.config/go-assessment/config.json.
+
+With --oidc-issuer the login goes through your company SSO instead: the CLI shows a
+code to enter in the browser and stores the ID token the identity provider returns.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := auth.Login(username, password); err != nil {
+		if oidcIssuer != "" {
+			if oidcClientID == "" {
+				fmt.Println("❌ Login failed: --oidc-client-id is required with --oidc-issuer")
+				return
+			}
+
+			err := auth.LoginOIDC(cmd.Context(), oidcIssuer, oidcClientID, func(authorization *oidc.DeviceAuthorization) {
+				if authorization.VerificationURIComplete != "" {
+					fmt.Printf("🌐 Open %s to log in\n", authorization.VerificationURIComplete)
+				}
+				fmt.Printf("🔑 Or go to %s and enter the code %s\n", authorization.VerificationURI, authorization.UserCode)
+				fmt.Println("⏳ Waiting for approval...")
+			})
+			if err != nil {
+				fmt.Printf("❌ Login failed: %v\n", err)
+				return
+			}
+
+			fmt.Println("✅ Login successful. Token stored.")
+			return
+		}
+
+		if username == "" || password == "" {
+			fmt.Println("❌ Login failed: --username and --password are required")
+			return
+		}
+
+		if err := auth.Login(serverURL, username, password); err != nil {
 			fmt.Printf("❌ Login failed: %v\n", err)
 			return
 		}
@@ -47,11 +81,9 @@ func init() {
 
 	loginCmd.Flags().StringVar(&username, "username", "", "Username for authentication")
 	loginCmd.Flags().StringVar(&password, "password", "", "Password for authentication")
+	loginCmd.Flags().StringVar(&oidcIssuer, "oidc-issuer", "", "Issuer URL of the OpenID provider to log in with")
+	loginCmd.Flags().StringVar(&oidcClientID, "oidc-client-id", "", "Client ID registered with the OpenID provider")
 
-	if err := loginCmd.MarkFlagRequired("username"); err != nil {
-		fmt.Printf("Error marking username flag as required: %v\n", err)
-	}
-	if err := loginCmd.MarkFlagRequired("password"); err != nil {
-		fmt.Printf("Error marking password flag as required: %v\n", err)
-	}
+	loginCmd.MarkFlagsRequiredTogether("username", "password")
+	loginCmd.MarkFlagsMutuallyExclusive("username", "oidc-issuer")
 }
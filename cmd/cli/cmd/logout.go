@@ -0,0 +1,53 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "End the current session",
+	Long:  `Revoke the current session on the API server and remove the stored tokens.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		// Revoke server-side first; the local tokens are removed either way
+		if err := c.Logout(); err != nil {
+			fmt.Printf("⚠️  Could not revoke the session on the server: %v\n", err)
+		}
+
+		if err := auth.ClearConfig(); err != nil {
+			fmt.Printf("❌ Logout failed: %v\n", err)
+			return
+		}
+
+		fmt.Println("👋 Logged out.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
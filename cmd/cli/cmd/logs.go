@@ -0,0 +1,92 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsName      string
+	logsFollow    bool
+	logsSince     time.Duration
+	logsTail      int64
+	logsContainer string
+	logsPrevious  bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the logs of a deployment",
+	Long: `Print the logs of every pod of a deployment, each line prefixed with its pod name.
+With -f, keep streaming new lines until interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		options := client.LogOptions{
+			Follow:    logsFollow,
+			Since:     logsSince,
+			Tail:      logsTail,
+			Container: logsContainer,
+			Previous:  logsPrevious,
+		}
+
+		ctx, cancel := waitContext(0)
+		defer cancel()
+
+		err = c.StreamLogs(ctx, logsName, options, func(line client.LogLine) bool {
+			if line.Error != "" {
+				fmt.Fprintf(os.Stderr, "[%s/%s] ❌ %s\n", line.Pod, line.Container, line.Error)
+				return true
+			}
+			fmt.Printf("[%s] %s\n", line.Pod, line.Line)
+			return true
+		})
+		if errors.Is(err, client.ErrNotFound) {
+			fmt.Printf("❌ Deployment %s not found\n", logsName)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to read logs: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().StringVar(&logsName, "name", "", "Name of the deployment to read the logs of")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep streaming new log lines")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "Only print lines newer than this duration, e.g. 10m")
+	logsCmd.Flags().Int64Var(&logsTail, "tail", -1, "Number of recent lines to print per container, all when negative")
+	logsCmd.Flags().StringVarP(&logsContainer, "container", "c", "", "Only print the logs of this container")
+	logsCmd.Flags().BoolVarP(&logsPrevious, "previous", "p", false, "Print the logs of the previous, crashed container instances")
+	if err := logsCmd.MarkFlagRequired("name"); err != nil {
+		fmt.Printf("Error marking name flag as required: %v\n", err)
+	}
+}
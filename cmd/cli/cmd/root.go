@@ -20,9 +20,17 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
 	"github.com/spf13/cobra"
 )
 
+// namespace scopes every command, set through the persistent --namespace flag
+var namespace string
+
+// serverURL is the API server every command talks to, set through the persistent --server flag
+var serverURL string
+
 var rootCmd = &cobra.Command{
 	Use:   "go-assessment",
 	Short: "CLI tool for authentication and deployment",
@@ -31,6 +39,25 @@ var rootCmd = &cobra.Command{
 	and destroy deployments.`,
 }
 
+// newClient returns an API client for the --server and --namespace flags that takes its
+// token from GO_ASSESSMENT_TOKEN or else the stored login, refreshing it when it is about to expire
+func newClient() (*client.Client, error) {
+	if token, ok := auth.TokenFromEnv(); ok {
+		c := client.NewClient(serverURL, token)
+		c.Namespace = namespace
+		return c, nil
+	}
+
+	if _, err := auth.LoadConfig(); err != nil {
+		return nil, err
+	}
+
+	c := client.NewClient(serverURL, "")
+	c.TokenSource = auth.NewTokenSource(serverURL)
+	c.Namespace = namespace
+	return c, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
@@ -47,4 +74,6 @@ func init() {
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "",
 	// 	"config file (default is $HOME/.config/go-assessment/config.json)")
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the application")
+	rootCmd.PersistentFlags().StringVar(&serverURL, "server", "http://localhost:8080", "URL of the API server")
 }
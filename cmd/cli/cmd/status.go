@@ -17,28 +17,33 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
-	"github.com/espinozasenior/go-assesstment.git/pkg/auth"
 	"github.com/espinozasenior/go-assesstment.git/pkg/client"
 	"github.com/spf13/cobra"
 )
 
 var statusName string
+var statusWatch bool
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Get the status of a deployment",
-	Long:  `Retrieve and display the status of a deployment.`,
+	Long: `Retrieve and display the status of a deployment.
+With --watch, follow its status changes until it is Running or Failed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		token, err := auth.GetToken()
+		c, err := newClient()
 		if err != nil {
 			fmt.Printf("❌ %v\n", err)
 			return
 		}
 
-		// Create a new client with the API server URL and token
-		c := client.NewClient("http://localhost:8080", token)
+		if statusWatch {
+			watchStatus(c, statusName)
+			return
+		}
 
 		// Get the status of the deployment
 		status, err := c.GetStatus(statusName)
@@ -51,10 +56,25 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// watchStatus follows the rollout of an app until it settles as Running or Failed
+func watchStatus(c *client.Client, name string) {
+	err := followRollout(c, name, 0)
+	if errors.Is(err, client.ErrNotFound) {
+		fmt.Printf("❌ Deployment %s not found\n", name)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %s is running\n", name)
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 
 	statusCmd.Flags().StringVar(&statusName, "name", "", "Name of the deployment to check status")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Follow status changes until the deployment is Running or Failed")
 	if err := statusCmd.MarkFlagRequired("name"); err != nil {
 		fmt.Printf("Error marking name flag as required: %v\n", err)
 	}
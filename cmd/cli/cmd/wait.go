@@ -0,0 +1,172 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+)
+
+// progressBarWidth is the number of cells of the replica progress bar
+const progressBarWidth = 20
+
+// waitContext returns a context cancelled by Ctrl-C and, when timeout is set, after timeout
+func waitContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// waitError explains why a wait ended early
+func waitError(ctx context.Context, timeout time.Duration, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s", timeout)
+	case ctx.Err() != nil:
+		return fmt.Errorf("interrupted")
+	default:
+		return err
+	}
+}
+
+// followRollout prints the progress of an app until it is Running with its latest spec, and
+// fails when it ends Failed, is deleted or timeout passes
+func followRollout(c *client.Client, name string, timeout time.Duration) error {
+	ctx, cancel := waitContext(timeout)
+	defer cancel()
+
+	progress := &rolloutProgress{}
+	var result error
+	err := c.WatchApp(ctx, name, func(event client.WatchEvent) bool {
+		if event.Type == client.WatchEventDeleted {
+			result = fmt.Errorf("%s was deleted", name)
+			return false
+		}
+
+		progress.print(event)
+
+		// Until the controller has seen the latest spec the state describes the previous one
+		if event.ObservedGeneration < event.Generation {
+			return true
+		}
+		switch event.Status {
+		case "Running":
+			return false
+		case "Failed":
+			result = fmt.Errorf("%s is Failed: %s", name, event.Message)
+			return false
+		}
+		return true
+	})
+	if ctx.Err() != nil {
+		return waitError(ctx, timeout, ctx.Err())
+	}
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// waitForDeletion prints the teardown progress of an app until it is gone
+func waitForDeletion(c *client.Client, name string, timeout time.Duration) error {
+	ctx, cancel := waitContext(timeout)
+	defer cancel()
+
+	progress := &rolloutProgress{}
+	deleted := false
+	err := c.WatchApp(ctx, name, func(event client.WatchEvent) bool {
+		if event.Type == client.WatchEventDeleted {
+			deleted = true
+			return false
+		}
+		progress.print(event)
+		return true
+	})
+	switch {
+	case deleted, errors.Is(err, client.ErrNotFound):
+		return nil
+	case ctx.Err() != nil:
+		return waitError(ctx, timeout, ctx.Err())
+	case err != nil:
+		return err
+	default:
+		return fmt.Errorf("watch ended before %s was deleted", name)
+	}
+}
+
+// rolloutProgress prints the changes between successive watch events
+type rolloutProgress struct {
+	last       string
+	conditions map[string]string
+}
+
+func (p *rolloutProgress) print(event client.WatchEvent) {
+	// The controller has not reported on a new app yet
+	status := event.Status
+	if status == "" {
+		status = "Created"
+	}
+
+	line := fmt.Sprintf("⏳ %-12s %s", status, replicaBar(event.Replicas, event.DesiredReplicas))
+	if event.Message != "" {
+		line += "  " + event.Message
+	}
+	if line != p.last {
+		fmt.Println(line)
+		p.last = line
+	}
+
+	// Report each problem condition once, when it appears or changes
+	if p.conditions == nil {
+		p.conditions = map[string]string{}
+	}
+	for _, condition := range event.Conditions {
+		key := string(condition.Status) + ": " + condition.Message
+		if p.conditions[condition.Type] == key {
+			continue
+		}
+		p.conditions[condition.Type] = key
+		if condition.Status == "True" {
+			fmt.Printf("   ⚠️  %s (%s): %s\n", condition.Type, condition.Reason, condition.Message)
+		}
+	}
+}
+
+// replicaBar renders available out of desired replicas as a progress bar
+func replicaBar(available, desired int32) string {
+	if desired <= 0 {
+		return fmt.Sprintf("%d replicas", available)
+	}
+	filled := int(available) * progressBarWidth / int(desired)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %d/%d replicas", strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), available, desired)
+}
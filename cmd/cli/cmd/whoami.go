@@ -0,0 +1,62 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the current identity",
+	Long:  `Display the user the API server associates with the stored token and when the token expires.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		whoami, err := c.WhoAmI()
+		if err != nil {
+			fmt.Printf("❌ Failed to get identity: %v\n", err)
+			return
+		}
+
+		fmt.Printf("👤 User: %s\n", whoami.Username)
+		if len(whoami.Groups) > 0 {
+			fmt.Printf("   Groups: %s\n", strings.Join(whoami.Groups, ", "))
+		}
+		if whoami.APIKeyID != "" {
+			fmt.Printf("   API key: %s\n", whoami.APIKeyID)
+		}
+		if whoami.ExpiresAt.IsZero() {
+			fmt.Println("   Token does not expire")
+			return
+		}
+		fmt.Printf("   Token expires: %s (in %s)\n", whoami.ExpiresAt.Local().Format(time.RFC1123),
+			time.Until(whoami.ExpiresAt).Round(time.Second))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
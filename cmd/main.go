@@ -17,20 +17,27 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -40,6 +47,7 @@ import (
 	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
 	"github.com/espinozasenior/go-assesstment.git/internal/apiserver"
 	"github.com/espinozasenior/go-assesstment.git/internal/controller"
+	"github.com/espinozasenior/go-assesstment.git/pkg/oidc"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -65,6 +73,15 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var apiServerPort int
+	var apiTokenKeyFile string
+	var apiUsersFile, apiUsersSecret string
+	var oidcIssuerURL, oidcClientID, oidcUsernameClaim, oidcUsernamePrefix, oidcGroupsClaim, oidcGroupsPrefix string
+	var apiRoleBindingsFile string
+	var apiAuthMode string
+	var apiAuthz string
+	var apiImpersonate bool
+	var apiKeysSecret string
+	var apiKeyMaxTTL time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -84,6 +101,37 @@ func main() {
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.IntVar(&apiServerPort, "api-server-port", 8080, "The port for the REST API server")
+	flag.StringVar(&apiTokenKeyFile, "api-token-key-file", "",
+		"File holding the key (at least 32 bytes) the REST API server signs access tokens with. "+
+			"A random key is generated when unset, invalidating tokens on every restart.")
+	flag.StringVar(&apiUsersFile, "api-users-file", "",
+		"JSON file listing the REST API users and their bcrypt password hashes.")
+	flag.StringVar(&apiUsersSecret, "api-users-secret", "",
+		"Secret (namespace/name) mapping REST API usernames to bcrypt password hashes.")
+	flag.StringVar(&oidcIssuerURL, "oidc-issuer-url", "",
+		"Issuer URL of an OpenID provider whose ID tokens the REST API accepts.")
+	flag.StringVar(&oidcClientID, "oidc-client-id", "", "Client ID the accepted OIDC ID tokens must be issued for.")
+	flag.StringVar(&oidcUsernameClaim, "oidc-username-claim", "email", "ID token claim used as the REST API user name.")
+	flag.StringVar(&oidcUsernamePrefix, "oidc-username-prefix", apiserver.DefaultOIDCUsernamePrefix,
+		"Prefix of the REST API user names taken from OIDC ID tokens; \"-\" for none.")
+	flag.StringVar(&oidcGroupsClaim, "oidc-groups-claim", "groups", "ID token claim listing the REST API user groups.")
+	flag.StringVar(&oidcGroupsPrefix, "oidc-groups-prefix", apiserver.DefaultOIDCGroupsPrefix,
+		"Prefix of the REST API groups taken from OIDC ID tokens; \"-\" for none.")
+	flag.StringVar(&apiRoleBindingsFile, "api-role-bindings-file", "",
+		"JSON file binding REST API users and groups to the viewer, editor and admin roles per namespace. "+
+			"Every API request is denied when unset, unless --api-authz=none.")
+	flag.StringVar(&apiAuthMode, "api-auth-mode", apiAuthModeBuiltin,
+		"How the REST API authenticates and authorizes callers: \"builtin\" for its own tokens, users and role bindings, "+
+			"or \"kubernetes\" to accept cluster tokens checked with TokenReview and authorize with SubjectAccessReview.")
+	flag.StringVar(&apiAuthz, "api-authz", "",
+		"Set to \"none\" to let every authenticated REST API caller do anything when there are no role bindings. "+
+			"Only meant for development.")
+	flag.BoolVar(&apiImpersonate, "api-impersonate", false,
+		"If set, the REST API impersonates the caller when changing AppDeployments. Requires the impersonate permission.")
+	flag.StringVar(&apiKeysSecret, "api-keys-secret", "",
+		"Secret (namespace/name) holding the hashed REST API keys. API keys are disabled when unset.")
+	flag.DurationVar(&apiKeyMaxTTL, "api-key-max-ttl", apiserver.DefaultAPIKeyMaxTTL,
+		"Longest lifetime of a REST API key; every key must expire within it.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -92,21 +140,6 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	// Start the REST API server in a goroutine
-	go func() {
-		server, err := apiserver.NewServer()
-		if err != nil {
-			setupLog.Error(err, "unable to create API server")
-			os.Exit(1)
-		}
-
-		setupLog.Info("starting API server (apiserver)", "port", apiServerPort)
-		if err := server.Start(apiServerPort); err != nil {
-			setupLog.Error(err, "problem running API server")
-			os.Exit(1)
-		}
-	}()
-
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -203,6 +236,11 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "f94eb19f.platform.deskree.com",
+		// Secrets are read straight from the API so that the manager never caches the content
+		// of every Secret in the cluster; the controller only watches their metadata
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -221,14 +259,95 @@ func main() {
 	}
 
 	if err = (&controller.AppDeploymentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor(controller.FieldManager),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AppDeployment")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
 
+	signingKey, err := loadTokenSigningKey(apiTokenKeyFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load API token signing key")
+		os.Exit(1)
+	}
+
+	server, err := apiserver.NewServer(mgr, fmt.Sprintf(":%d", apiServerPort), signingKey)
+	if err != nil {
+		setupLog.Error(err, "unable to create API server")
+		os.Exit(1)
+	}
+
+	server.Users, err = loadUserStore(server, apiUsersFile, apiUsersSecret)
+	if err != nil {
+		setupLog.Error(err, "unable to load API users")
+		os.Exit(1)
+	}
+
+	if oidcIssuerURL != "" {
+		// The audience of every ID token is checked against the client ID, so none would pass without it
+		if oidcClientID == "" {
+			setupLog.Error(fmt.Errorf("--oidc-issuer-url requires --oidc-client-id"), "unable to configure OIDC")
+			os.Exit(1)
+		}
+		provider, err := oidc.Discover(context.Background(), nil, oidcIssuerURL)
+		if err != nil {
+			setupLog.Error(err, "unable to discover OIDC provider")
+			os.Exit(1)
+		}
+		server.Authenticator = apiserver.Authenticators{
+			server.Tokens,
+			&apiserver.OIDCAuthenticator{
+				Verifier:       oidc.NewVerifier(provider, oidcClientID, nil),
+				UsernameClaim:  oidcUsernameClaim,
+				UsernamePrefix: oidcUsernamePrefix,
+				GroupsClaim:    oidcGroupsClaim,
+				GroupsPrefix:   oidcGroupsPrefix,
+			},
+		}
+		setupLog.Info("accepting OIDC ID tokens", "issuer", oidcIssuerURL, "clientID", oidcClientID)
+	}
+
+	if apiRoleBindingsFile != "" {
+		server.Authorizer, err = apiserver.LoadRoleBindings(apiRoleBindingsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load API role bindings")
+			os.Exit(1)
+		}
+	}
+
+	if err := configureAPIAuthMode(server, mgr, apiAuthMode, apiImpersonate); err != nil {
+		setupLog.Error(err, "unable to configure API authentication")
+		os.Exit(1)
+	}
+	if err := configureAPIAuthz(server, apiAuthz); err != nil {
+		setupLog.Error(err, "unable to configure API authorization")
+		os.Exit(1)
+	}
+
+	if apiKeysSecret != "" {
+		namespace, name, found := strings.Cut(apiKeysSecret, "/")
+		if !found || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("--api-keys-secret must be namespace/name, got %q", apiKeysSecret), "unable to configure API keys")
+			os.Exit(1)
+		}
+		server.APIKeys = &apiserver.SecretAPIKeyStore{
+			Client: server.Client, Reader: mgr.GetAPIReader(), Namespace: namespace, Name: name,
+		}
+		server.APIKeyMaxTTL = apiKeyMaxTTL
+		server.Authenticator = apiserver.Authenticators{
+			&apiserver.APIKeyAuthenticator{Store: server.APIKeys, Users: server.Users}, server.Authenticator,
+		}
+	}
+
+	// The REST API runs with the manager, sharing its cache and stopping with it
+	if err := mgr.Add(server); err != nil {
+		setupLog.Error(err, "unable to add API server to manager")
+		os.Exit(1)
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -253,6 +372,10 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("apiserver", server.ReadyCheck); err != nil {
+		setupLog.Error(err, "unable to set up API server ready check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -260,3 +383,102 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadTokenSigningKey reads the API token signing key from path, or generates a random one
+// when no path is given
+func loadTokenSigningKey(path string) ([]byte, error) {
+	if path == "" {
+		setupLog.Info("no --api-token-key-file given, generating a random token signing key")
+		return apiserver.GenerateSigningKey()
+	}
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(key), nil
+}
+
+// loadUserStore returns the user store backing password login, from a file or a Secret.
+// Password login stays disabled when neither is given.
+func loadUserStore(server *apiserver.Server, file, secret string) (apiserver.UserStore, error) {
+	switch {
+	case file != "" && secret != "":
+		return nil, fmt.Errorf("--api-users-file and --api-users-secret are mutually exclusive")
+	case file != "":
+		return apiserver.LoadFileUserStore(file)
+	case secret != "":
+		namespace, name, found := strings.Cut(secret, "/")
+		if !found || namespace == "" || name == "" {
+			return nil, fmt.Errorf("--api-users-secret must be namespace/name, got %q", secret)
+		}
+		return &apiserver.SecretUserStore{Client: server.Client, Namespace: namespace, Name: name}, nil
+	default:
+		setupLog.Info("no API user store configured, password login is disabled")
+		return nil, nil
+	}
+}
+
+// apiAuthzNone is the --api-authz value turning REST API authorization off
+const apiAuthzNone = "none"
+
+// configureAPIAuthz makes an API server without an Authorizer deny every request, unless
+// authorization is explicitly turned off with --api-authz=none
+func configureAPIAuthz(server *apiserver.Server, authz string) error {
+	switch authz {
+	case "":
+		if server.Authorizer == nil {
+			server.Authorizer = &apiserver.RoleAuthorizer{}
+			setupLog.Info("WARNING: no API role bindings, every API request is denied; " +
+				"set --api-role-bindings-file, or --api-authz=none to allow every authenticated caller")
+		}
+	case apiAuthzNone:
+		if server.Authorizer != nil {
+			return fmt.Errorf("--api-authz=none cannot be combined with role bindings or --api-auth-mode=kubernetes")
+		}
+		setupLog.Info("WARNING: API authorization is off, every authenticated caller may do anything")
+	default:
+		return fmt.Errorf("unknown --api-authz %q, expected %q or nothing", authz, apiAuthzNone)
+	}
+	return nil
+}
+
+// REST API auth modes
+const (
+	apiAuthModeBuiltin    = "builtin"
+	apiAuthModeKubernetes = "kubernetes"
+)
+
+// configureAPIAuthMode switches the API server to Kubernetes TokenReview and
+// SubjectAccessReview in the kubernetes mode, and turns on impersonation of the caller
+func configureAPIAuthMode(server *apiserver.Server, mgr ctrl.Manager, mode string, impersonate bool) error {
+	switch mode {
+	case apiAuthModeBuiltin:
+	case apiAuthModeKubernetes:
+		if server.Users != nil || server.Authorizer != nil {
+			return fmt.Errorf("--api-auth-mode=kubernetes cannot be combined with API users or role bindings")
+		}
+		if _, ok := server.Authenticator.(*apiserver.TokenIssuer); !ok {
+			return fmt.Errorf("--api-auth-mode=kubernetes cannot be combined with an OIDC issuer, configure it on the cluster")
+		}
+		server.Authenticator = &apiserver.TokenReviewAuthenticator{Client: server.Client}
+		server.Authorizer = &apiserver.SubjectAccessReviewAuthorizer{Client: server.Client}
+		setupLog.Info("delegating API authentication and authorization to Kubernetes")
+	default:
+		return fmt.Errorf("unknown --api-auth-mode %q, expected %q or %q", mode, apiAuthModeBuiltin, apiAuthModeKubernetes)
+	}
+
+	if impersonate {
+		impersonating, err := apiserver.ImpersonatingClient(mgr.GetConfig(), server.Client)
+		if err != nil {
+			return err
+		}
+		server.Impersonate = impersonating
+		impersonatingPods, err := apiserver.ImpersonatingPods(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+		server.ImpersonatePods = impersonatingPods
+		setupLog.Info("impersonating API callers")
+	}
+	return nil
+}
@@ -0,0 +1,571 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// APIKeyPrefix starts every API key, telling them apart from the other bearer tokens
+const APIKeyPrefix = "gak_"
+
+// lastUsedResolution bounds how often the last-used time of a key is written back
+const lastUsedResolution = time.Minute
+
+// DefaultAPIKeyMaxTTL is the longest lifetime of an API key unless the server sets another.
+// Keys of users without a UserStore entry keep the groups they were created with, so every
+// key has to expire.
+const DefaultAPIKeyMaxTTL = 90 * 24 * time.Hour
+
+// ErrAPIKeyNotFound is returned for an API key ID the store does not hold
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKeyScope limits what a caller authenticated with an API key may do
+type APIKeyScope struct {
+	// Namespaces the key may act in, AllNamespaces for every namespace
+	Namespaces []string `json:"namespaces"`
+	// Verbs the key may perform on apps
+	Verbs []string `json:"verbs"`
+}
+
+// Allows reports whether the scope covers verb in namespace
+func (s *APIKeyScope) Allows(verb, namespace string) bool {
+	return (containsString(s.Namespaces, namespace) || containsString(s.Namespaces, AllNamespaces)) &&
+		containsString(s.Verbs, verb)
+}
+
+// APIKey describes a long-lived API key; the key itself is only shown when it is created
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Owner      string     `json:"owner"`
+	Namespaces []string   `json:"namespaces"`
+	Verbs      []string   `json:"verbs"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// StoredAPIKey is an API key as held by an APIKeyStore
+type StoredAPIKey struct {
+	APIKey
+	// OwnerGroups are the groups of the owner when the key was created
+	OwnerGroups []string `json:"ownerGroups,omitempty"`
+	// LocalOwner is set when the owner is a user of the server's UserStore, whose current
+	// groups then apply instead of OwnerGroups
+	LocalOwner bool `json:"localOwner,omitempty"`
+	// Hash is the hex SHA-256 of the key; keys are random 256-bit secrets, so a fast hash suffices
+	Hash string `json:"hash"`
+}
+
+// APIKeyList is the response of GET /api/v1/apikeys
+type APIKeyList struct {
+	Items []APIKey `json:"items"`
+}
+
+// CreateAPIKeyRequest asks for a new API key for the caller
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name"`
+	Namespaces []string   `json:"namespaces"`
+	Verbs      []string   `json:"verbs"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreatedAPIKey is the response of POST /api/v1/apikeys, the only one carrying the key
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyStore holds the hashed API keys
+type APIKeyStore interface {
+	Create(ctx context.Context, key *StoredAPIKey) error
+	// Get returns the key with the given ID, ErrAPIKeyNotFound when there is none
+	Get(ctx context.Context, id string) (*StoredAPIKey, error)
+	List(ctx context.Context) ([]StoredAPIKey, error)
+	Delete(ctx context.Context, id string) error
+	MarkUsed(ctx context.Context, id string, at time.Time) error
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
+
+// SecretAPIKeyStore keeps API keys in a Kubernetes Secret, one data entry per key ID holding
+// the JSON of the StoredAPIKey. The Secret is created with the first key.
+type SecretAPIKeyStore struct {
+	Client client.Client
+	// Reader reads the Secret, Client when nil. An uncached reader lets updates start from the
+	// latest version instead of retrying on conflicts until a cache catches up.
+	Reader    client.Reader
+	Namespace string
+	Name      string
+}
+
+func (s *SecretAPIKeyStore) read(ctx context.Context) (*corev1.Secret, error) {
+	reader := s.Reader
+	if reader == nil {
+		reader = s.Client
+	}
+
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: s.Namespace}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// update applies change to the Secret, retrying on conflicts with concurrent writers
+func (s *SecretAPIKeyStore) update(ctx context.Context, change func(secret *corev1.Secret) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := s.read(ctx)
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		if err := change(secret); err != nil {
+			return err
+		}
+		return s.Client.Update(ctx, secret)
+	})
+}
+
+// Create implements APIKeyStore
+func (s *SecretAPIKeyStore) Create(ctx context.Context, key *StoredAPIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("error encoding API key: %v", err)
+	}
+
+	err = s.update(ctx, func(secret *corev1.Secret) error {
+		secret.Data[key.ID] = data
+		return nil
+	})
+	if apierrors.IsNotFound(err) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			Data:       map[string][]byte{key.ID: data},
+		}
+		err = s.Client.Create(ctx, secret)
+	}
+	if err != nil {
+		return fmt.Errorf("error storing API key in secret %s/%s: %v", s.Namespace, s.Name, err)
+	}
+	return nil
+}
+
+// Get implements APIKeyStore
+func (s *SecretAPIKeyStore) Get(ctx context.Context, id string) (*StoredAPIKey, error) {
+	secret, err := s.read(ctx)
+	if apierrors.IsNotFound(err) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading API keys secret %s/%s: %v", s.Namespace, s.Name, err)
+	}
+
+	data, ok := secret.Data[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	key := &StoredAPIKey{}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("error decoding API key %s: %v", id, err)
+	}
+	return key, nil
+}
+
+// List implements APIKeyStore
+func (s *SecretAPIKeyStore) List(ctx context.Context) ([]StoredAPIKey, error) {
+	secret, err := s.read(ctx)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading API keys secret %s/%s: %v", s.Namespace, s.Name, err)
+	}
+
+	keys := make([]StoredAPIKey, 0, len(secret.Data))
+	for id, data := range secret.Data {
+		var key StoredAPIKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			apiLog.Error(err, "Skipping undecodable API key", "id", id)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Delete implements APIKeyStore
+func (s *SecretAPIKeyStore) Delete(ctx context.Context, id string) error {
+	err := s.update(ctx, func(secret *corev1.Secret) error {
+		if _, ok := secret.Data[id]; !ok {
+			return ErrAPIKeyNotFound
+		}
+		delete(secret.Data, id)
+		return nil
+	})
+	if apierrors.IsNotFound(err) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// MarkUsed implements APIKeyStore
+func (s *SecretAPIKeyStore) MarkUsed(ctx context.Context, id string, at time.Time) error {
+	return s.update(ctx, func(secret *corev1.Secret) error {
+		data, ok := secret.Data[id]
+		if !ok {
+			return ErrAPIKeyNotFound
+		}
+		var key StoredAPIKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			return fmt.Errorf("error decoding API key %s: %v", id, err)
+		}
+		key.LastUsedAt = &at
+		data, err := json.Marshal(key)
+		if err != nil {
+			return fmt.Errorf("error encoding API key: %v", err)
+		}
+		secret.Data[id] = data
+		return nil
+	})
+}
+
+// APIKeyAuthenticator accepts the API keys of a store as bearer tokens
+type APIKeyAuthenticator struct {
+	Store APIKeyStore
+	// Users resolves the current groups of keys owned by local users, and rejects their keys
+	// once the user is removed
+	Users UserStore
+
+	mu sync.Mutex
+	// marked holds when the use of each key was last recorded, so that concurrent requests
+	// with a key write it back once per lastUsedResolution
+	marked map[string]time.Time
+}
+
+// Authenticate implements Authenticator. The caller acts as the owner of the key, limited to
+// the scope of the key.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := parseAPIKey(token)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := a.Store.Get(r.Context(), id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(token)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	groups := key.OwnerGroups
+	if key.LocalOwner && a.Users != nil {
+		owner, err := a.Users.Lookup(r.Context(), key.Owner)
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, fmt.Errorf("%w: owner %q of API key %s no longer exists", ErrInvalidToken, key.Owner, key.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		groups = owner.Groups
+	}
+
+	// Recording the use is a write to the store, kept off the request path
+	if a.shouldMarkUsed(key, now) {
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := a.Store.MarkUsed(ctx, id, now); err != nil {
+				apiLog.Error(err, "Failed to record API key use", "id", id)
+			}
+		}()
+	}
+
+	identity := &Identity{
+		Subject:  key.Owner,
+		Groups:   groups,
+		APIKeyID: key.ID,
+		Scope:    &APIKeyScope{Namespaces: key.Namespaces, Verbs: key.Verbs},
+	}
+	if key.ExpiresAt != nil {
+		identity.ExpiresAt = *key.ExpiresAt
+	}
+	return identity, nil
+}
+
+// shouldMarkUsed reports whether the use of key at now is to be recorded, which happens at
+// most once per lastUsedResolution for each key
+func (a *APIKeyAuthenticator) shouldMarkUsed(key *StoredAPIKey, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	last := a.marked[key.ID]
+	if key.LastUsedAt != nil && key.LastUsedAt.After(last) {
+		last = *key.LastUsedAt
+	}
+	if now.Sub(last) < lastUsedResolution {
+		return false
+	}
+
+	if a.marked == nil {
+		a.marked = map[string]time.Time{}
+	}
+	for id, at := range a.marked {
+		if now.Sub(at) >= lastUsedResolution {
+			delete(a.marked, id)
+		}
+	}
+	a.marked[key.ID] = now
+	return true
+}
+
+// newAPIKey returns a random key and its ID
+func newAPIKey() (key, id string, err error) {
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", fmt.Errorf("error generating API key: %v", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("error generating API key: %v", err)
+	}
+
+	id = hex.EncodeToString(idBytes)
+	return APIKeyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret), id, nil
+}
+
+// parseAPIKey returns the ID of a key of the form gak_<id>_<secret>
+func parseAPIKey(token string) (string, bool) {
+	rest, found := strings.CutPrefix(token, APIKeyPrefix)
+	if !found {
+		return "", false
+	}
+	id, secret, found := strings.Cut(rest, "_")
+	if !found || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleCreateAPIKey creates an API key owned by the caller
+func (s *Server) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	identity, ok := s.apiKeyCaller(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if req.Name == "" || len(req.Namespaces) == 0 || len(req.Verbs) == 0 {
+		writeError(w, http.StatusBadRequest, "Name, namespaces and verbs are required")
+		return
+	}
+	for _, verb := range req.Verbs {
+		if !containsString(apiKeyVerbs, verb) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Unknown verb %q, expected one of %s", verb, strings.Join(apiKeyVerbs, ", ")))
+			return
+		}
+	}
+	maxTTL := s.APIKeyMaxTTL
+	if maxTTL == 0 {
+		maxTTL = DefaultAPIKeyMaxTTL
+	}
+	switch now := time.Now(); {
+	case req.ExpiresAt == nil:
+		writeError(w, http.StatusBadRequest, "expiresAt is required")
+		return
+	case req.ExpiresAt.Before(now):
+		writeError(w, http.StatusBadRequest, "expiresAt must be in the future")
+		return
+	case req.ExpiresAt.After(now.Add(maxTTL)):
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("expiresAt must be within %s", maxTTL))
+		return
+	}
+
+	key, id, err := newAPIKey()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Keys of local users follow the user's groups, other owners keep the groups they had
+	localOwner := false
+	if s.Users != nil {
+		_, err := s.Users.Lookup(r.Context(), identity.Subject)
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			apiLog.Error(err, "Failed to look up API key owner", "owner", identity.Subject)
+			writeError(w, http.StatusInternalServerError, "Failed to look up API key owner")
+			return
+		}
+		localOwner = err == nil
+	}
+
+	stored := &StoredAPIKey{
+		APIKey: APIKey{
+			ID:         id,
+			Name:       req.Name,
+			Owner:      identity.Subject,
+			Namespaces: req.Namespaces,
+			Verbs:      req.Verbs,
+			CreatedAt:  time.Now().UTC().Truncate(time.Second),
+			ExpiresAt:  req.ExpiresAt,
+		},
+		OwnerGroups: identity.Groups,
+		LocalOwner:  localOwner,
+		Hash:        hashAPIKey(key),
+	}
+	if err := s.APIKeys.Create(r.Context(), stored); err != nil {
+		apiLog.Error(err, "Failed to store API key", "owner", identity.Subject)
+		writeError(w, http.StatusInternalServerError, "Failed to store API key")
+		return
+	}
+
+	apiLog.Info("API key created", "id", id, "name", req.Name, "owner", identity.Subject)
+	writeJSON(w, http.StatusCreated, CreatedAPIKey{APIKey: stored.APIKey, Key: key})
+}
+
+// HandleListAPIKeys lists the API keys of the caller
+func (s *Server) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	identity, ok := s.apiKeyCaller(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := s.APIKeys.List(r.Context())
+	if err != nil {
+		apiLog.Error(err, "Failed to list API keys")
+		writeError(w, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+
+	list := APIKeyList{Items: []APIKey{}}
+	for _, key := range keys {
+		if key.Owner == identity.Subject {
+			list.Items = append(list.Items, key.APIKey)
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// HandleRevokeAPIKey deletes an API key of the caller
+func (s *Server) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	identity, ok := s.apiKeyCaller(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+
+	key, err := s.APIKeys.Get(r.Context(), id)
+	if errors.Is(err, ErrAPIKeyNotFound) || (err == nil && key.Owner != identity.Subject) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("API key %s not found", id))
+		return
+	}
+	if err == nil {
+		err = s.APIKeys.Delete(r.Context(), id)
+	}
+	if err != nil {
+		apiLog.Error(err, "Failed to revoke API key", "id", id)
+		writeError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	apiLog.Info("API key revoked", "id", id, "owner", identity.Subject)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("API key %s revoked", id),
+	})
+}
+
+// apiKeyVerbs are the verbs an API key may be scoped to
+var apiKeyVerbs = []string{VerbGet, VerbList, VerbWatch, VerbCreate, VerbUpdate, VerbPatch, VerbDelete, VerbLogs}
+
+// apiKeyCaller returns the caller of an API key endpoint, writing an error response when
+// API keys are disabled or the request is not authenticated. The scope of an API key only
+// covers apps, so API keys cannot be created, listed or revoked with an API key.
+func (s *Server) apiKeyCaller(w http.ResponseWriter, r *http.Request) (*Identity, bool) {
+	if s.APIKeys == nil {
+		writeError(w, http.StatusNotImplemented, "API keys are not configured on this server")
+		return nil, false
+	}
+
+	identity, ok := IdentityFrom(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized: request is not authenticated")
+		return nil, false
+	}
+	if identity.APIKeyID != "" {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: API key %s may not manage API keys", identity.APIKeyID))
+		return nil, false
+	}
+	return identity, true
+}
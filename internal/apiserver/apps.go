@@ -0,0 +1,384 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// The /api/v1 apps resource is read as the AppDeployment itself (full spec and status) and
+// written in the DeployRequest form: POST and PUT take a DeployRequest, PATCH takes a JSON
+// merge patch against the DeployRequest form of the current AppDeployment.
+
+// HandleListApps lists the AppDeployments of a namespace
+func (s *Server) HandleListApps(w http.ResponseWriter, r *http.Request) {
+	appDeployments := &deskreev1.AppDeploymentList{}
+	if err := s.readerFor(r).List(r.Context(), appDeployments, client.InNamespace(RequestNamespace(r))); err != nil {
+		apiLog.Error(err, "Failed to list AppDeployments", "namespace", RequestNamespace(r))
+		writeAPIError(w, err, "Failed to list AppDeployments")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, appDeployments)
+}
+
+// HandleCreateApp creates an AppDeployment from a DeployRequest
+func (s *Server) HandleCreateApp(w http.ResponseWriter, r *http.Request) {
+	var req DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if fields := prepareDeployRequest(&req); len(fields) > 0 {
+		writeFieldErrors(w, "Invalid deploy request", fields)
+		return
+	}
+
+	opts, err := createOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	appDeployment := newAppDeployment(RequestNamespace(r), req)
+	setCreator(r, appDeployment)
+	if err := s.clientFor(r).Create(r.Context(), appDeployment, opts...); err != nil {
+		writeAPIError(w, err, "Failed to create AppDeployment")
+		return
+	}
+
+	setETag(w, appDeployment)
+	writeJSON(w, http.StatusCreated, appDeployment)
+}
+
+// HandleGetApp returns the full spec and status of an AppDeployment
+func (s *Server) HandleGetApp(w http.ResponseWriter, r *http.Request) {
+	// Read as the caller, like the list, so a caller only sees what its own access allows
+	appDeployment := &deskreev1.AppDeployment{}
+	key := types.NamespacedName{Name: r.PathValue("name"), Namespace: RequestNamespace(r)}
+	if err := s.readerFor(r).Get(r.Context(), key, appDeployment); err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	}
+
+	setETag(w, appDeployment)
+	writeJSON(w, http.StatusOK, appDeployment)
+}
+
+// HandleUpdateApp replaces the user-facing spec fields of an AppDeployment with a DeployRequest
+func (s *Server) HandleUpdateApp(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = name
+	}
+	s.updateApp(w, r, VerbUpdate, req, nil)
+}
+
+// HandlePatchApp applies a JSON merge patch to the DeployRequest form of an AppDeployment
+func (s *Server) HandlePatchApp(w http.ResponseWriter, r *http.Request) {
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+
+	if contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); contentType != "application/merge-patch+json" && contentType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "PATCH requires Content-Type application/merge-patch+json")
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	appDeployment := &deskreev1.AppDeployment{}
+	if err := s.latestReaderFor(r).Get(r.Context(), types.NamespacedName{Name: name, Namespace: namespace}, appDeployment); err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	}
+
+	current, err := json.Marshal(deployRequestFromAppDeployment(appDeployment))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to encode AppDeployment: %v", err))
+		return
+	}
+
+	patched, err := jsonpatch.MergePatch(current, patch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid merge patch: %v", err))
+		return
+	}
+
+	var req DeployRequest
+	if err := json.Unmarshal(patched, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid merge patch: %v", err))
+		return
+	}
+
+	// Raising minReplicas alone carries maxReplicas along, as when it is left out on create
+	var patchFields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &patchFields); err == nil {
+		if _, ok := patchFields["maxReplicas"]; !ok && req.MaxReplicas < req.MinReplicas {
+			req.MaxReplicas = 0
+		}
+	}
+
+	// Updating the version the patch was computed against turns concurrent changes into conflicts
+	s.updateApp(w, r, VerbPatch, req, appDeployment)
+}
+
+// updateApp writes a full DeployRequest onto the named AppDeployment, read first unless given.
+// The update carries the resourceVersion read, so a concurrent change fails with a conflict
+// instead of being overwritten, and the caller must be allowed verb on that version.
+func (s *Server) updateApp(w http.ResponseWriter, r *http.Request, verb string, req DeployRequest, appDeployment *deskreev1.AppDeployment) {
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+
+	if req.Name != name {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Name %q does not match the path, names are immutable", req.Name))
+		return
+	}
+
+	if fields := prepareDeployRequest(&req); len(fields) > 0 {
+		writeFieldErrors(w, "Invalid deploy request", fields)
+		return
+	}
+
+	opts, err := updateOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if appDeployment == nil {
+		appDeployment = &deskreev1.AppDeployment{}
+		if err := s.latestReaderFor(r).Get(r.Context(), types.NamespacedName{Name: name, Namespace: namespace}, appDeployment); err != nil {
+			writeAPIError(w, err, "Failed to get AppDeployment")
+			return
+		}
+	}
+	if !checkIfMatch(w, r, appDeployment) {
+		return
+	}
+	// The app may have been replaced since the caller's access was checked
+	if err := s.authorizeApp(r, verb, appDeployment); err != nil {
+		writeAPIError(w, err, "Failed to update AppDeployment")
+		return
+	}
+
+	setAppDeploymentSpec(&appDeployment.Spec, req)
+	if err := s.clientFor(r).Update(r.Context(), appDeployment, opts...); err != nil {
+		writeAPIError(w, err, "Failed to update AppDeployment")
+		return
+	}
+
+	setETag(w, appDeployment)
+	writeJSON(w, http.StatusOK, appDeployment)
+}
+
+// HandleDeleteApp deletes an AppDeployment; the controller tears down its resources
+func (s *Server) HandleDeleteApp(w http.ResponseWriter, r *http.Request) {
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+
+	opts, ok := s.deleteOptions(w, r, namespace, name)
+	if !ok {
+		return
+	}
+
+	appDeployment := &deskreev1.AppDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	if err := s.clientFor(r).Delete(r.Context(), appDeployment, opts...); err != nil {
+		writeAPIError(w, err, "Failed to delete AppDeployment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("AppDeployment %s/%s deletion started", namespace, name),
+	})
+}
+
+// prepareDeployRequest validates a deploy request and fills in the replica defaults, returning
+// the invalid fields if any
+func prepareDeployRequest(req *DeployRequest) []FieldError {
+	if fields := validateDeployRequest(*req); len(fields) > 0 {
+		return fields
+	}
+
+	if req.MinReplicas == 0 {
+		req.MinReplicas = 1
+	}
+
+	if req.MaxReplicas == 0 {
+		req.MaxReplicas = req.MinReplicas
+	}
+
+	return nil
+}
+
+// isDryRun reports whether a request asks for a server-side dry run with ?dryRun=All, the only
+// value the Kubernetes API accepts. A dry run is validated and admitted by the cluster as a
+// real request would be, but nothing is persisted.
+func isDryRun(r *http.Request) (bool, error) {
+	values, ok := r.URL.Query()["dryRun"]
+	if !ok {
+		return false, nil
+	}
+	for _, value := range values {
+		if value != metav1.DryRunAll {
+			return false, fmt.Errorf("Invalid dryRun %q, the only supported value is %s", value, metav1.DryRunAll)
+		}
+	}
+	return true, nil
+}
+
+// createOptions returns the options of a create request, a server-side dry run if asked for
+func createOptions(r *http.Request) ([]client.CreateOption, error) {
+	dryRun, err := isDryRun(r)
+	if err != nil || !dryRun {
+		return nil, err
+	}
+	return []client.CreateOption{client.DryRunAll}, nil
+}
+
+// updateOptions returns the options of an update request, a server-side dry run if asked for
+func updateOptions(r *http.Request) ([]client.UpdateOption, error) {
+	dryRun, err := isDryRun(r)
+	if err != nil || !dryRun {
+		return nil, err
+	}
+	return []client.UpdateOption{client.DryRunAll}, nil
+}
+
+// newAppDeployment builds the AppDeployment a deploy request describes
+func newAppDeployment(namespace string, req DeployRequest) *deskreev1.AppDeployment {
+	appDeployment := &deskreev1.AppDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       req.Name,
+				"app.kubernetes.io/managed-by": "go-assessment-api",
+			},
+		},
+		Spec: deskreev1.AppDeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": req.Name,
+				},
+			},
+			Template: deskreev1.PodTemplateSpec{
+				ObjectMeta: deskreev1.ObjectMeta{
+					Labels: map[string]string{
+						"app": req.Name,
+					},
+				},
+			},
+		},
+	}
+
+	setAppDeploymentSpec(&appDeployment.Spec, req)
+	return appDeployment
+}
+
+// setAppDeploymentSpec writes the fields a deploy request controls onto an AppDeployment spec,
+// leaving everything else (selector, adoption, drift policy, hooks) as it is
+func setAppDeploymentSpec(spec *deskreev1.AppDeploymentSpec, req DeployRequest) {
+	spec.MemoryLimit = req.MemoryLimit
+	spec.MinReplicas = req.MinReplicas
+	spec.MaxReplicas = req.MaxReplicas
+
+	var envFrom []deskreev1.EnvFromSource
+	for _, configMap := range req.ConfigMaps {
+		envFrom = append(envFrom, deskreev1.EnvFromSource{ConfigMapRef: &deskreev1.LocalObjectReference{Name: configMap}})
+	}
+	for _, secret := range req.Secrets {
+		envFrom = append(envFrom, deskreev1.EnvFromSource{SecretRef: &deskreev1.LocalObjectReference{Name: secret}})
+	}
+
+	if len(spec.Template.Spec.Containers) == 0 {
+		spec.Template.Spec.Containers = []deskreev1.Container{
+			{
+				Name: req.Name,
+				Ports: []deskreev1.ContainerPort{
+					{
+						ContainerPort: 80,
+					},
+				},
+			},
+		}
+	}
+
+	container := &spec.Template.Spec.Containers[0]
+	container.Image = req.Image
+	container.EnvFrom = envFrom
+}
+
+// deployRequestFromAppDeployment returns the DeployRequest form of an existing AppDeployment
+func deployRequestFromAppDeployment(app *deskreev1.AppDeployment) DeployRequest {
+	req := DeployRequest{
+		Name:        app.Name,
+		MemoryLimit: app.Spec.MemoryLimit,
+		MinReplicas: app.Spec.MinReplicas,
+		MaxReplicas: app.Spec.MaxReplicas,
+	}
+
+	if len(app.Spec.Template.Spec.Containers) > 0 {
+		container := app.Spec.Template.Spec.Containers[0]
+		req.Image = container.Image
+		for _, source := range container.EnvFrom {
+			switch {
+			case source.ConfigMapRef != nil:
+				req.ConfigMaps = append(req.ConfigMaps, source.ConfigMapRef.Name)
+			case source.SecretRef != nil:
+				req.Secrets = append(req.Secrets, source.SecretRef.Name)
+			}
+		}
+	}
+
+	return req
+}
+
+// writeJSON encodes body as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		apiLog.Error(err, "Failed to encode response")
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenIssuerName is the "iss" claim of the tokens the API server signs
+const TokenIssuerName = "go-assessment-apiserver"
+
+// DefaultTokenTTL is how long an access token issued by the API server stays valid
+const DefaultTokenTTL = time.Hour
+
+// DefaultRefreshTokenTTL is how long a refresh token issued by the API server stays valid
+const DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+// Token uses, carried in the "use" claim so a refresh token cannot stand in for an access token
+const (
+	tokenUseAccess  = "access"
+	tokenUseRefresh = "refresh"
+)
+
+var (
+	// ErrMissingToken is returned when a request carries no bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken is returned for malformed tokens and tokens with a bad signature
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for well-formed tokens past their expiry
+	ErrExpiredToken = errors.New("token expired")
+	// ErrRevokedToken is returned for tokens of a session that was logged out
+	ErrRevokedToken = errors.New("token revoked")
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	// Subject is the user name the token was issued to
+	Subject string `json:"sub"`
+	// UID of the user, set by authenticators that know it
+	UID string `json:"uid,omitempty"`
+	// Groups the user belongs to
+	Groups []string `json:"groups,omitempty"`
+	// Extra holds additional user information, such as the Kubernetes token review extras
+	Extra map[string][]string `json:"extra,omitempty"`
+	// SessionID ties together the access and refresh tokens of one login
+	SessionID string `json:"sid,omitempty"`
+	// ExpiresAt is the expiry of the token the identity was read from
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// APIKeyID is set when the caller authenticated with an API key
+	APIKeyID string `json:"apiKeyID,omitempty"`
+	// Scope limits what the caller may do, set for API keys
+	Scope *APIKeyScope `json:"scope,omitempty"`
+}
+
+// Authenticator resolves the caller of a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Authenticators tries each authenticator in turn and accepts the first identity found
+type Authenticators []Authenticator
+
+// Authenticate implements Authenticator. When every authenticator rejects the request, the
+// most specific reason is reported: a token recognised but expired or revoked by one
+// authenticator is more telling than the others finding it invalid.
+func (a Authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	var reason error
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(r)
+		if err == nil {
+			return identity, nil
+		}
+		if reason == nil || errors.Is(reason, ErrInvalidToken) || errors.Is(reason, ErrMissingToken) {
+			reason = err
+		}
+	}
+	if reason == nil {
+		reason = ErrInvalidToken
+	}
+	return nil, reason
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the caller identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the caller identity attached to ctx by the authentication middleware
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// Authenticate wraps next so that every request must carry a valid bearer token. Rejected
+// requests get a 401; accepted ones reach next with the caller identity in their context.
+func Authenticate(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := authenticator.Authenticate(r)
+		if err != nil {
+			apiLog.V(1).Info("Rejected unauthenticated request", "method", r.Method, "path", r.URL.Path, "reason", err.Error())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-assessment"`)
+			writeError(w, http.StatusUnauthorized, fmt.Sprintf("Unauthorized: %v", err))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// BearerToken returns the token of an "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrMissingToken
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingToken
+	}
+
+	return strings.TrimSpace(token), nil
+}
+
+// TokenIssuer signs and verifies the HS256 JSON Web Tokens the API server hands out
+type TokenIssuer struct {
+	key []byte
+	ttl time.Duration
+	// RefreshTTL is the lifetime of refresh tokens, DefaultRefreshTokenTTL unless changed
+	RefreshTTL time.Duration
+
+	mu sync.Mutex
+	// revoked maps the logged out session IDs to the time their last token expires. It is
+	// held in memory, so a restart forgets revocations unless the signing key is rotated.
+	revoked map[string]time.Time
+}
+
+// tokenClaims is the payload of an issued token
+type tokenClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Groups    []string `json:"groups,omitempty"`
+	SessionID string   `json:"sid"`
+	Use       string   `json:"use"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// tokenHeader is the fixed JOSE header of an issued token
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// NewTokenIssuer returns an issuer signing with key whose tokens are valid for ttl
+func NewTokenIssuer(key []byte, ttl time.Duration) (*TokenIssuer, error) {
+	if len(key) < 32 {
+		return nil, fmt.Errorf("token signing key must be at least 32 bytes, got %d", len(key))
+	}
+	return &TokenIssuer{key: key, ttl: ttl, RefreshTTL: DefaultRefreshTokenTTL, revoked: map[string]time.Time{}}, nil
+}
+
+// GenerateSigningKey returns a random key suitable for NewTokenIssuer
+func GenerateSigningKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error generating signing key: %v", err)
+	}
+	return key, nil
+}
+
+// NewSessionID returns a random ID for the tokens of a new login
+func NewSessionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating session ID: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// Issue signs an access token for identity and returns it with its expiry time
+func (t *TokenIssuer) Issue(identity Identity) (string, time.Time, error) {
+	return t.issue(identity, tokenUseAccess, t.ttl)
+}
+
+// IssueRefresh signs a refresh token for identity and returns it with its expiry time
+func (t *TokenIssuer) IssueRefresh(identity Identity) (string, time.Time, error) {
+	return t.issue(identity, tokenUseRefresh, t.RefreshTTL)
+}
+
+// Verify checks the signature and expiry of an access token and returns the identity it was issued to
+func (t *TokenIssuer) Verify(token string) (*Identity, error) {
+	return t.verify(token, tokenUseAccess)
+}
+
+// VerifyRefresh checks a refresh token and returns the identity it was issued to
+func (t *TokenIssuer) VerifyRefresh(token string) (*Identity, error) {
+	return t.verify(token, tokenUseRefresh)
+}
+
+// Revoke rejects every token of a session from now on
+func (t *TokenIssuer) Revoke(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for id, until := range t.revoked {
+		if now.After(until) {
+			delete(t.revoked, id)
+		}
+	}
+	t.revoked[sessionID] = now.Add(t.RefreshTTL)
+}
+
+func (t *TokenIssuer) isRevoked(sessionID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.revoked[sessionID]
+	return ok
+}
+
+func (t *TokenIssuer) issue(identity Identity, use string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	payload, err := json.Marshal(tokenClaims{
+		Issuer:    TokenIssuerName,
+		Subject:   identity.Subject,
+		Groups:    identity.Groups,
+		SessionID: identity.SessionID,
+		Use:       use,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error encoding token claims: %v", err)
+	}
+
+	signingInput := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + t.sign(signingInput), expiresAt, nil
+}
+
+func (t *TokenIssuer) verify(token, use string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(t.sign(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != TokenIssuerName || claims.Use != use || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	if claims.SessionID != "" && t.isRevoked(claims.SessionID) {
+		return nil, ErrRevokedToken
+	}
+
+	return &Identity{
+		Subject:   claims.Subject,
+		Groups:    claims.Groups,
+		SessionID: claims.SessionID,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// Authenticate implements Authenticator for tokens signed by this issuer
+func (t *TokenIssuer) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return t.Verify(token)
+}
+
+func (t *TokenIssuer) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
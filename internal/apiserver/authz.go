@@ -0,0 +1,370 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// CreatedByAnnotation records the user that created an AppDeployment through the API
+const CreatedByAnnotation = "deskree.platform.deskree.com/created-by"
+
+// Roles, mirroring config/rbac/appdeployment_{viewer,editor,admin}_role.yaml
+const (
+	// RoleViewer may read apps
+	RoleViewer = "viewer"
+	// RoleEditor may read apps, create apps and modify or delete the apps it created
+	RoleEditor = "editor"
+	// RoleAdmin may do anything to any app of the namespace
+	RoleAdmin = "admin"
+)
+
+// Verbs of the API operations on apps
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbWatch  = "watch"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+	// VerbLogs lets an API key read the logs of the pods of apps
+	VerbLogs = "logs"
+)
+
+// Pod logs are authorized as get on the pods/log subresource, like kubectl logs
+const (
+	podsResource   = "pods"
+	logSubresource = "log"
+)
+
+// AllNamespaces in a role binding grants the role in every namespace
+const AllNamespaces = "*"
+
+// Attributes describe an operation to authorize
+type Attributes struct {
+	Verb      string
+	Namespace string
+	// Name of the app, empty for list and create
+	Name string
+	// Creator of the app as recorded in CreatedByAnnotation, for operations on existing apps
+	Creator string
+	// Resource and Subresource name the core resource of the app's pods the operation reads,
+	// such as pods/log; both are empty for operations on the app itself
+	Resource    string
+	Subresource string
+}
+
+// Decision is the outcome of an authorization check; Reason explains a denial
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+// Authorizer decides whether a caller may perform an operation
+type Authorizer interface {
+	Authorize(ctx context.Context, identity *Identity, attributes Attributes) (Decision, error)
+}
+
+// RoleBinding grants a role to users and groups in a namespace
+type RoleBinding struct {
+	// Namespace the role applies to, AllNamespaces for every namespace
+	Namespace string   `json:"namespace"`
+	Role      string   `json:"role"`
+	Users     []string `json:"users,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+}
+
+// RoleAuthorizer authorizes operations from a static list of role bindings
+type RoleAuthorizer struct {
+	Bindings []RoleBinding
+}
+
+// LoadRoleBindings reads a JSON array of role bindings from path
+func LoadRoleBindings(path string) (*RoleAuthorizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading role bindings file: %v", err)
+	}
+
+	var bindings []RoleBinding
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return nil, fmt.Errorf("error parsing role bindings file: %v", err)
+	}
+
+	for i, binding := range bindings {
+		if roleRank(binding.Role) == 0 {
+			return nil, fmt.Errorf("role binding %d: unknown role %q", i, binding.Role)
+		}
+		if binding.Namespace == "" {
+			return nil, fmt.Errorf("role binding %d: namespace is required, use %q for all namespaces", i, AllNamespaces)
+		}
+	}
+
+	return &RoleAuthorizer{Bindings: bindings}, nil
+}
+
+// roleRank orders roles so that each one includes the permissions of the lower ones
+func roleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// RoleFor returns the highest role bound to identity in namespace, or "" when there is none
+func (a *RoleAuthorizer) RoleFor(identity *Identity, namespace string) string {
+	role := ""
+	for _, binding := range a.Bindings {
+		if binding.Namespace != namespace && binding.Namespace != AllNamespaces {
+			continue
+		}
+		if !bindingMatches(binding, identity) {
+			continue
+		}
+		if roleRank(binding.Role) > roleRank(role) {
+			role = binding.Role
+		}
+	}
+	return role
+}
+
+func bindingMatches(binding RoleBinding, identity *Identity) bool {
+	for _, user := range binding.Users {
+		if user == identity.Subject {
+			return true
+		}
+	}
+	for _, group := range binding.Groups {
+		for _, member := range identity.Groups {
+			if group == member {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorize implements Authorizer
+func (a *RoleAuthorizer) Authorize(_ context.Context, identity *Identity, attributes Attributes) (Decision, error) {
+	role := a.RoleFor(identity, attributes.Namespace)
+	if role == "" {
+		return Decision{Reason: fmt.Sprintf("user %q has no role in namespace %q", identity.Subject, attributes.Namespace)}, nil
+	}
+
+	// Logs may hold what only the people working on an app should see
+	if attributes.Resource != "" {
+		if roleRank(role) >= roleRank(RoleEditor) {
+			return Decision{Allowed: true}, nil
+		}
+		return Decision{Reason: fmt.Sprintf("user %q has role %s in namespace %q, which does not allow reading %s/%s",
+			identity.Subject, role, attributes.Namespace, attributes.Resource, attributes.Subresource)}, nil
+	}
+
+	switch attributes.Verb {
+	case VerbGet, VerbList, VerbWatch:
+		return Decision{Allowed: true}, nil
+	case VerbCreate:
+		if roleRank(role) >= roleRank(RoleEditor) {
+			return Decision{Allowed: true}, nil
+		}
+	case VerbUpdate, VerbPatch, VerbDelete:
+		if role == RoleAdmin {
+			return Decision{Allowed: true}, nil
+		}
+		if role == RoleEditor {
+			if attributes.Creator == identity.Subject {
+				return Decision{Allowed: true}, nil
+			}
+			return Decision{Reason: fmt.Sprintf("user %q is an editor in namespace %q and may only %s apps it created; %s was created by %s",
+				identity.Subject, attributes.Namespace, attributes.Verb, attributes.Name, creatorName(attributes.Creator))}, nil
+		}
+	}
+
+	return Decision{Reason: fmt.Sprintf("user %q has role %s in namespace %q, which does not allow %s",
+		identity.Subject, role, attributes.Namespace, attributes.Verb)}, nil
+}
+
+func creatorName(creator string) string {
+	if creator == "" {
+		return "an unknown user"
+	}
+	return fmt.Sprintf("%q", creator)
+}
+
+// authorize wraps a route so that the caller must be allowed verb on the app it addresses.
+// API keys are held to their scope; otherwise requests pass through unchecked when the server
+// has no Authorizer.
+func (s *Server) authorize(verb string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.checkAccess(w, r, verb, r.PathValue("name")) {
+			next(w, r)
+		}
+	}
+}
+
+// checkAccess tells whether the caller may perform verb on the named app, or on the apps of
+// the request namespace when name is empty. When it may not, the 401 or 403 response has
+// been sent.
+func (s *Server) checkAccess(w http.ResponseWriter, r *http.Request, verb, name string) bool {
+	identity, ok := IdentityFrom(r.Context())
+	if ok && identity.Scope != nil && !identity.Scope.Allows(verb, RequestNamespace(r)) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: API key %s may not %s apps in namespace %q",
+			identity.APIKeyID, verb, RequestNamespace(r)))
+		return false
+	}
+
+	if s.Authorizer == nil {
+		return true
+	}
+
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized: request is not authenticated")
+		return false
+	}
+
+	attributes := Attributes{
+		Verb:      verb,
+		Namespace: RequestNamespace(r),
+		Name:      name,
+	}
+
+	// Operations on an existing app are checked against its recorded creator, read from the
+	// cluster like the write that follows so a lagging cache cannot hide a new app. An app
+	// that is not found has no creator, which leaves it to callers who may change any app.
+	if attributes.Name != "" && verb != VerbGet && verb != VerbWatch {
+		appDeployment := &deskreev1.AppDeployment{}
+		err := s.latestReaderFor(r).Get(r.Context(), types.NamespacedName{Name: attributes.Name, Namespace: attributes.Namespace}, appDeployment)
+		if err != nil && !apierrors.IsNotFound(err) {
+			writeAPIError(w, err, "Failed to get AppDeployment")
+			return false
+		}
+		attributes.Creator = appDeployment.Annotations[CreatedByAnnotation]
+	}
+
+	return s.decide(w, r, identity, attributes)
+}
+
+// decide asks the Authorizer whether the caller may perform an operation. When it may not,
+// the 403 response has been sent.
+func (s *Server) decide(w http.ResponseWriter, r *http.Request, identity *Identity, attributes Attributes) bool {
+	decision, err := s.Authorizer.Authorize(r.Context(), identity, attributes)
+	if err != nil {
+		apiLog.Error(err, "Failed to authorize request", "user", identity.Subject, "verb", attributes.Verb)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to authorize request: %v", err))
+		return false
+	}
+	if !decision.Allowed {
+		apiLog.Info("Denied request", "user", identity.Subject, "verb", attributes.Verb,
+			"namespace", attributes.Namespace, "name", attributes.Name, "reason", decision.Reason)
+		writeError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: %s", decision.Reason))
+		return false
+	}
+
+	return true
+}
+
+// authorizeApp checks that the caller may perform verb on an app it has just read, against the
+// creator recorded on that very object, and returns a Forbidden error when it may not
+func (s *Server) authorizeApp(r *http.Request, verb string, appDeployment *deskreev1.AppDeployment) error {
+	if s.Authorizer == nil {
+		return nil
+	}
+	identity, ok := IdentityFrom(r.Context())
+	if !ok {
+		return apierrors.NewUnauthorized("request is not authenticated")
+	}
+
+	decision, err := s.Authorizer.Authorize(r.Context(), identity, Attributes{
+		Verb:      verb,
+		Namespace: appDeployment.Namespace,
+		Name:      appDeployment.Name,
+		Creator:   appDeployment.Annotations[CreatedByAnnotation],
+	})
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return apierrors.NewForbidden(deskreev1.GroupVersion.WithResource("appdeployments").GroupResource(), appDeployment.Name,
+			errors.New(decision.Reason))
+	}
+	return nil
+}
+
+// authorizeLogs wraps the logs route so that the caller must also be allowed to read the
+// logs of the app's pods: API keys need the logs verb, and the Authorizer is asked for get on
+// pods/log in the namespace
+func (s *Server) authorizeLogs(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespace := RequestNamespace(r)
+		identity, ok := IdentityFrom(r.Context())
+		if ok && identity.Scope != nil && !identity.Scope.Allows(VerbLogs, namespace) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: API key %s may not read logs in namespace %q",
+				identity.APIKeyID, namespace))
+			return
+		}
+
+		if s.Authorizer == nil {
+			next(w, r)
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Unauthorized: request is not authenticated")
+			return
+		}
+
+		attributes := Attributes{
+			Verb:        VerbGet,
+			Namespace:   namespace,
+			Name:        r.PathValue("name"),
+			Resource:    podsResource,
+			Subresource: logSubresource,
+		}
+		if s.decide(w, r, identity, attributes) {
+			next(w, r)
+		}
+	}
+}
+
+// setCreator records the caller as the creator of a new AppDeployment
+func setCreator(r *http.Request, appDeployment *deskreev1.AppDeployment) {
+	identity, ok := IdentityFrom(r.Context())
+	if !ok {
+		return
+	}
+
+	if appDeployment.Annotations == nil {
+		appDeployment.Annotations = map[string]string{}
+	}
+	appDeployment.Annotations[CreatedByAnnotation] = identity.Subject
+}
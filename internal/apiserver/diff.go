@@ -0,0 +1,159 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+	"github.com/espinozasenior/go-assesstment.git/internal/controller"
+)
+
+// DiffResponse lists what applying a deploy request would change. In each change, Desired is
+// the value the request produces and Live the current one.
+type DiffResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Exists is false when the request would create the app
+	Exists bool `json:"exists"`
+	// Changes lists the fields of the AppDeployment and of its Deployment that would change
+	Changes []deskreev1.FieldDiff `json:"changes,omitempty"`
+}
+
+// HandleDiffApp compares a DeployRequest against the live AppDeployment and Deployment without
+// changing anything. The Deployment is compared on the fields the controller sets, as in the
+// drift report.
+func (s *Server) HandleDiffApp(w http.ResponseWriter, r *http.Request) {
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+
+	var req DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = name
+	}
+	if req.Name != name {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Name %q does not match the path, names are immutable", req.Name))
+		return
+	}
+
+	if fields := prepareDeployRequest(&req); len(fields) > 0 {
+		writeFieldErrors(w, "Invalid deploy request", fields)
+		return
+	}
+
+	response := DiffResponse{Name: name, Namespace: namespace}
+
+	live := &deskreev1.AppDeployment{}
+	err := s.readerFor(r).Get(r.Context(), types.NamespacedName{Name: name, Namespace: namespace}, live)
+	switch {
+	case apierrors.IsNotFound(err):
+		live = nil
+	case err != nil:
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	default:
+		response.Exists = true
+	}
+
+	var proposed *deskreev1.AppDeployment
+	if live != nil {
+		proposed = live.DeepCopy()
+		setAppDeploymentSpec(&proposed.Spec, req)
+	} else {
+		proposed = newAppDeployment(namespace, req)
+	}
+	response.Changes = diffAppDeployment(live, proposed)
+
+	// The Deployment is read with the server's own access, as the controller would see it
+	var liveDeployment *appsv1.Deployment
+	deployment := &appsv1.Deployment{}
+	err = s.reader().Get(r.Context(), types.NamespacedName{Name: controller.DeploymentName(proposed), Namespace: namespace}, deployment)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		writeAPIError(w, err, "Failed to get Deployment")
+		return
+	default:
+		liveDeployment = deployment
+	}
+
+	deploymentChanges, err := controller.PreviewDeployment(proposed, liveDeployment)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to build Deployment: %v", err))
+		return
+	}
+	response.Changes = append(response.Changes, deploymentChanges...)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// diffAppDeployment compares the fields a deploy request controls on an AppDeployment. A nil
+// live AppDeployment reports every field with an empty live value.
+func diffAppDeployment(live, proposed *deskreev1.AppDeployment) []deskreev1.FieldDiff {
+	resource := fmt.Sprintf("AppDeployment/%s", proposed.Name)
+	var diffs []deskreev1.FieldDiff
+	add := func(path, desiredValue, liveValue string) {
+		if desiredValue != liveValue {
+			diffs = append(diffs, deskreev1.FieldDiff{Resource: resource, Path: path, Desired: desiredValue, Live: liveValue})
+		}
+	}
+
+	want := deployRequestFromAppDeployment(proposed)
+	var got DeployRequest
+	if live != nil {
+		got = deployRequestFromAppDeployment(live)
+	}
+
+	container := fmt.Sprintf("spec.template.spec.containers[%s]", proposed.Spec.Template.Spec.Containers[0].Name)
+	add(container+".image", want.Image, got.Image)
+	add(container+".envFrom", envFromNames(want), envFromNames(got))
+	add("spec.memoryLimit", want.MemoryLimit, got.MemoryLimit)
+	add("spec.minReplicas", replicasString(want.MinReplicas), replicasString(got.MinReplicas))
+	add("spec.maxReplicas", replicasString(want.MaxReplicas), replicasString(got.MaxReplicas))
+	return diffs
+}
+
+// envFromNames lists the ConfigMaps and Secrets of a deploy request as in the drift report
+func envFromNames(req DeployRequest) string {
+	values := make([]string, 0, len(req.ConfigMaps)+len(req.Secrets))
+	for _, configMap := range req.ConfigMaps {
+		values = append(values, "configmap/"+configMap)
+	}
+	for _, secret := range req.Secrets {
+		values = append(values, "secret/"+secret)
+	}
+	return strings.Join(values, ",")
+}
+
+// replicasString formats a replica count, leaving unset counts empty
+func replicasString(replicas int32) string {
+	if replicas == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", replicas)
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Machine-readable error codes carried in the Code of an ErrorResponse
+const (
+	CodeBadRequest           = "BadRequest"
+	CodeUnauthorized         = "Unauthorized"
+	CodeForbidden            = "Forbidden"
+	CodeNotFound             = "NotFound"
+	CodeMethodNotAllowed     = "MethodNotAllowed"
+	CodeAlreadyExists        = "AlreadyExists"
+	CodeConflict             = "Conflict"
+	CodeGone                 = "Gone"
+	CodePreconditionFailed   = "PreconditionFailed"
+	CodeUnsupportedMediaType = "UnsupportedMediaType"
+	CodeInvalid              = "Invalid"
+	CodeTooManyRequests      = "TooManyRequests"
+	CodeInternalError        = "InternalError"
+	CodeNotImplemented       = "NotImplemented"
+	CodeServiceUnavailable   = "ServiceUnavailable"
+	CodeTimeout              = "Timeout"
+)
+
+// ErrorResponse is the body of every error returned by the API
+type ErrorResponse struct {
+	// Status is always "error", so clients of the original {"status":"error","message":...}
+	// shape keep working
+	Status string `json:"status"`
+	// Code classifies the error independently of its message, e.g. "NotFound" or "Conflict"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields lists the offending fields of a rejected request, if any
+	Fields []FieldError `json:"fields,omitempty"`
+	// Retryable reports whether the same request may succeed if sent again later
+	Retryable bool `json:"retryable"`
+}
+
+// FieldError describes why a single field of a request was rejected
+type FieldError struct {
+	// Field is the path of the field, e.g. "spec.replicas"
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorCodes maps HTTP status codes to the error code reported for them when no more
+// specific code is known
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusGone:                  CodeGone,
+	http.StatusPreconditionFailed:    CodePreconditionFailed,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   CodeInvalid,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusInternalServerError:   CodeInternalError,
+	http.StatusNotImplemented:        CodeNotImplemented,
+	http.StatusServiceUnavailable:    CodeServiceUnavailable,
+	http.StatusGatewayTimeout:        CodeTimeout,
+	http.StatusRequestEntityTooLarge: CodeBadRequest,
+}
+
+// reasonErrors maps the reason of a Kubernetes API error to the HTTP status code and error
+// code returned to the caller
+var reasonErrors = map[metav1.StatusReason]struct {
+	status int
+	code   string
+}{
+	metav1.StatusReasonBadRequest:            {http.StatusBadRequest, CodeBadRequest},
+	metav1.StatusReasonUnauthorized:          {http.StatusUnauthorized, CodeUnauthorized},
+	metav1.StatusReasonForbidden:             {http.StatusForbidden, CodeForbidden},
+	metav1.StatusReasonNotFound:              {http.StatusNotFound, CodeNotFound},
+	metav1.StatusReasonMethodNotAllowed:      {http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	metav1.StatusReasonAlreadyExists:         {http.StatusConflict, CodeAlreadyExists},
+	metav1.StatusReasonConflict:              {http.StatusConflict, CodeConflict},
+	metav1.StatusReasonGone:                  {http.StatusGone, CodeGone},
+	metav1.StatusReasonExpired:               {http.StatusGone, CodeGone},
+	metav1.StatusReasonUnsupportedMediaType:  {http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+	metav1.StatusReasonInvalid:               {http.StatusUnprocessableEntity, CodeInvalid},
+	metav1.StatusReasonRequestEntityTooLarge: {http.StatusRequestEntityTooLarge, CodeBadRequest},
+	metav1.StatusReasonTooManyRequests:       {http.StatusTooManyRequests, CodeTooManyRequests},
+	metav1.StatusReasonInternalError:         {http.StatusInternalServerError, CodeInternalError},
+	metav1.StatusReasonServiceUnavailable:    {http.StatusServiceUnavailable, CodeServiceUnavailable},
+	metav1.StatusReasonTimeout:               {http.StatusGatewayTimeout, CodeTimeout},
+	metav1.StatusReasonServerTimeout:         {http.StatusGatewayTimeout, CodeTimeout},
+}
+
+// retryable reports whether a request failing with the given error code may succeed if sent
+// again: conflicts are resolved by retrying with fresh state, the rest are transient
+func retryable(code string) bool {
+	switch code {
+	case CodeConflict, CodeTooManyRequests, CodeServiceUnavailable, CodeTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeError sends an error response whose code is derived from the HTTP status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = CodeInternalError
+	}
+	writeJSON(w, status, ErrorResponse{
+		Status:    "error",
+		Code:      code,
+		Message:   message,
+		Retryable: retryable(code),
+	})
+}
+
+// writeFieldErrors rejects a request with 422 Unprocessable Entity, listing the offending fields
+func writeFieldErrors(w http.ResponseWriter, message string, fields []FieldError) {
+	writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+		Status:  "error",
+		Code:    CodeInvalid,
+		Message: message,
+		Fields:  fields,
+	})
+}
+
+// writeAPIError sends a failed Kubernetes API call to the caller, prefixing the error with the
+// action that failed. The status code and error code follow the reason of the error, the
+// causes of an Invalid error become field errors, and errors that are not Kubernetes API
+// errors are reported as internal errors
+func writeAPIError(w http.ResponseWriter, err error, action string) {
+	response := errorResponseFor(err, action)
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok && response.Retryable {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	writeJSON(w, statusCodeFor(err), response)
+}
+
+// statusCodeFor maps a Kubernetes API error to the HTTP status code returned to the caller
+func statusCodeFor(err error) int {
+	if mapped, ok := reasonErrors[apierrors.ReasonForError(err)]; ok {
+		return mapped.status
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponseFor builds the error response for a failed Kubernetes API call
+func errorResponseFor(err error, action string) ErrorResponse {
+	response := ErrorResponse{
+		Status:  "error",
+		Code:    CodeInternalError,
+		Message: fmt.Sprintf("%s: %v", action, err),
+	}
+	if mapped, ok := reasonErrors[apierrors.ReasonForError(err)]; ok {
+		response.Code = mapped.code
+	}
+	response.Retryable = retryable(response.Code)
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			if cause.Field == "" {
+				continue
+			}
+			response.Fields = append(response.Fields, FieldError{Field: cause.Field, Message: cause.Message})
+		}
+	}
+	return response
+}
+
+// statusRecorder captures the status code and headers a handler writes without sending them,
+// so that the plain-text errors of http.ServeMux can be replaced
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *statusRecorder) Header() http.Header         { return r.header }
+func (r *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (r *statusRecorder) WriteHeader(status int)      { r.status = status }
+
+// withErrorResponses serves requests through mux, answering requests that match no route
+// with an ErrorResponse instead of the plain-text 404 and 405 responses of http.ServeMux
+func withErrorResponses(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{header: http.Header{}, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		switch recorder.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", recorder.header.Get("Allow"))
+			writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s not allowed for %s", r.Method, r.URL.Path))
+		case http.StatusNotFound:
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No route for %s %s", r.Method, r.URL.Path))
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
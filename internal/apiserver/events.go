@@ -0,0 +1,133 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/internal/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=list
+
+// AppEvent is a Kubernetes Event about an app or one of the objects it runs on
+type AppEvent struct {
+	// Time the event last occurred
+	Time metav1.Time `json:"time"`
+	// Type is Normal or Warning
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	// Object the event is about, as Kind/name
+	Object  string `json:"object"`
+	Message string `json:"message"`
+	// Count of the occurrences folded into this event
+	Count int32 `json:"count,omitempty"`
+}
+
+// AppEventList is the response of the events endpoint
+type AppEventList struct {
+	Items []AppEvent `json:"items"`
+}
+
+// HandleAppEvents returns the Events of an app, its Deployment and the ReplicaSets and pods of
+// that Deployment, oldest first
+func (s *Server) HandleAppEvents(w http.ResponseWriter, r *http.Request) {
+	if s.APIReader == nil {
+		writeError(w, http.StatusServiceUnavailable, "Reading events is not available on this server")
+		return
+	}
+
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+	app, err := s.getAppDeployment(r.Context(), namespace, name)
+	if err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	}
+
+	deploymentName := controller.DeploymentName(app)
+	objects := map[string]bool{
+		"AppDeployment/" + app.Name:    true,
+		"Deployment/" + deploymentName: true,
+	}
+
+	// Events are not cached, so the whole namespace is listed once and matched here
+	events := &corev1.EventList{}
+	if err := s.APIReader.List(r.Context(), events, client.InNamespace(namespace)); err != nil {
+		writeAPIError(w, err, "Failed to list events")
+		return
+	}
+
+	list := AppEventList{Items: []AppEvent{}}
+	for _, event := range events.Items {
+		object := event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name
+		if !objects[object] && !ownedByDeployment(deploymentName, event.InvolvedObject) {
+			continue
+		}
+		list.Items = append(list.Items, AppEvent{
+			Time:    metav1.Time{Time: eventTime(event)},
+			Type:    event.Type,
+			Reason:  event.Reason,
+			Object:  object,
+			Message: event.Message,
+			Count:   event.Count,
+		})
+	}
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].Time.Before(&list.Items[j].Time)
+	})
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ownedByDeployment tells whether an event is about a ReplicaSet or pod of a Deployment, going
+// by the names the Deployment controller gives them: <deployment>-<hash> for ReplicaSets and
+// <deployment>-<hash>-<suffix> for their pods. Names are used rather than labels so that the
+// events of pods already replaced are kept.
+func ownedByDeployment(deploymentName string, ref corev1.ObjectReference) bool {
+	rest, found := strings.CutPrefix(ref.Name, deploymentName+"-")
+	if !found || rest == "" {
+		return false
+	}
+	switch ref.Kind {
+	case "ReplicaSet":
+		return !strings.Contains(rest, "-")
+	case "Pod":
+		return strings.Count(rest, "-") == 1
+	default:
+		return false
+	}
+}
+
+// eventTime returns when an event last occurred, whichever of its timestamps is set
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
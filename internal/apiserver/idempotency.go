@@ -0,0 +1,283 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader names the header a client sets to make a create request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyWindow is how long outcomes are remembered when the store sets no Window
+	DefaultIdempotencyWindow = 24 * time.Hour
+	// DefaultIdempotencyKeysPerCaller bounds the keys remembered for one caller when the store
+	// sets no MaxKeysPerCaller
+	DefaultIdempotencyKeysPerCaller = 1000
+	// DefaultIdempotencyKeys bounds the keys remembered in total when the store sets no MaxKeys
+	DefaultIdempotencyKeys = 100000
+
+	// maxIdempotencyKeyLength bounds the keys clients may send
+	maxIdempotencyKeyLength = 255
+)
+
+// errIdempotencyStoreFull is returned when a new key would exceed the limits of the store
+var errIdempotencyStoreFull = errors.New("too many idempotency keys")
+
+// IdempotencyStore remembers the outcome of requests sent with an Idempotency-Key header, so
+// that a retried request is answered with the original outcome instead of being applied
+// again. Outcomes are kept in memory, per caller and key, for Window; they are not shared
+// between API server replicas. The zero value is ready to use.
+type IdempotencyStore struct {
+	// Window is how long an outcome is remembered, DefaultIdempotencyWindow when zero
+	Window time.Duration
+	// MaxKeysPerCaller bounds the keys remembered for one caller, DefaultIdempotencyKeysPerCaller
+	// when zero
+	MaxKeysPerCaller int
+	// MaxKeys bounds the keys remembered in total, DefaultIdempotencyKeys when zero
+	MaxKeys int
+
+	mu       sync.Mutex
+	outcomes map[idempotencyKey]*idempotentOutcome
+	// callers counts the keys of each caller
+	callers map[string]int
+	// expiries holds the finished outcomes in the order they were finished, which with a fixed
+	// window is the order they expire in
+	expiries list.List
+}
+
+// idempotencyKey is a key as sent by a caller
+type idempotencyKey struct {
+	caller string
+	key    string
+}
+
+// idempotentOutcome is the response to a request with an idempotency key, or a placeholder
+// while the request is in progress
+type idempotentOutcome struct {
+	key idempotencyKey
+	// fingerprint identifies the request the key was first used with
+	fingerprint string
+	done        bool
+	code        int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// begin looks up the outcome of the key of a caller. When the key is new, it is reserved for
+// the request with the given fingerprint and nil is returned, or errIdempotencyStoreFull when
+// the caller or the store holds too many keys already.
+func (s *IdempotencyStore) begin(caller, key, fingerprint string) (*idempotentOutcome, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	storeKey := idempotencyKey{caller: caller, key: key}
+	if outcome, ok := s.outcomes[storeKey]; ok {
+		copied := *outcome
+		return &copied, nil
+	}
+
+	maxKeysPerCaller := s.MaxKeysPerCaller
+	if maxKeysPerCaller == 0 {
+		maxKeysPerCaller = DefaultIdempotencyKeysPerCaller
+	}
+	maxKeys := s.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = DefaultIdempotencyKeys
+	}
+	if s.callers[caller] >= maxKeysPerCaller || len(s.outcomes) >= maxKeys {
+		return nil, errIdempotencyStoreFull
+	}
+
+	if s.outcomes == nil {
+		s.outcomes = map[idempotencyKey]*idempotentOutcome{}
+		s.callers = map[string]int{}
+	}
+	s.outcomes[storeKey] = &idempotentOutcome{key: storeKey, fingerprint: fingerprint}
+	s.callers[caller]++
+	return nil, nil
+}
+
+// expire forgets the outcomes whose window ended by now
+func (s *IdempotencyStore) expire(now time.Time) {
+	for element := s.expiries.Front(); element != nil; element = s.expiries.Front() {
+		outcome := element.Value.(*idempotentOutcome)
+		if !now.After(outcome.expires) {
+			return
+		}
+		s.expiries.Remove(element)
+		s.remove(outcome.key)
+	}
+}
+
+// remove forgets the outcome of a key
+func (s *IdempotencyStore) remove(key idempotencyKey) {
+	delete(s.outcomes, key)
+	s.callers[key.caller]--
+	if s.callers[key.caller] <= 0 {
+		delete(s.callers, key.caller)
+	}
+}
+
+// finish records the response to the request that reserved the key of a caller. Only
+// successful responses are remembered; any other response releases the key, so the request
+// can be corrected or retried with it, as does a handler that panicked before responding.
+func (s *IdempotencyStore) finish(caller, key string, code int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	storeKey := idempotencyKey{caller: caller, key: key}
+	outcome, ok := s.outcomes[storeKey]
+	if !ok || outcome.done {
+		return
+	}
+	if code < http.StatusOK || code >= http.StatusMultipleChoices {
+		s.remove(storeKey)
+		return
+	}
+
+	window := s.Window
+	if window == 0 {
+		window = DefaultIdempotencyWindow
+	}
+	outcome.done = true
+	outcome.code = code
+	outcome.header = header
+	outcome.body = body
+	outcome.expires = time.Now().Add(window)
+	s.expiries.PushBack(outcome)
+}
+
+// responseCapture passes a response through while keeping a copy of it
+type responseCapture struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	c.code = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.code == 0 {
+		c.code = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// requestFingerprint identifies a request by its target and body. JSON bodies are compared
+// regardless of key order and formatting.
+func requestFingerprint(r *http.Request, body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s %s\n", r.Method, r.URL.Path, r.URL.Query().Encode())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotent wraps a create route so that requests with an Idempotency-Key header are applied
+// once: a retry with the same key gets the remembered response, marked with the
+// Idempotent-Replayed header. Reusing a key for a different request is rejected with 422, and
+// a retry while the first request is still in progress with 409. Requests without the header,
+// or on a server without an IdempotencyStore, pass through.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if s.Idempotency == nil || key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		// The whole body is held to fingerprint it, so it is bounded like any other
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit))
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped to the caller, and a key stands for one request: target and body
+		caller := ""
+		if identity, ok := IdentityFrom(r.Context()); ok {
+			caller = identity.Subject
+		}
+		fingerprint := requestFingerprint(r, body)
+
+		outcome, err := s.Idempotency.begin(caller, key, fingerprint)
+		switch {
+		case err != nil:
+			writeError(w, http.StatusTooManyRequests, fmt.Sprintf("Too many %s values in use, retry later or without one", IdempotencyKeyHeader))
+			return
+		case outcome == nil:
+		case outcome.fingerprint != fingerprint:
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s %q was already used for a different request", IdempotencyKeyHeader, key))
+			return
+		case !outcome.done:
+			writeError(w, http.StatusConflict, fmt.Sprintf("A request with %s %q is still in progress", IdempotencyKeyHeader, key))
+			return
+		default:
+			for name, values := range outcome.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(outcome.code)
+			if _, err := w.Write(outcome.body); err != nil {
+				apiLog.Error(err, "Failed to write replayed response")
+			}
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		defer func() {
+			s.Idempotency.finish(caller, key, capture.code, w.Header().Clone(), capture.body.Bytes())
+		}()
+		next(capture, r)
+	}
+}
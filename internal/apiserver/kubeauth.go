@@ -0,0 +1,247 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// appDeploymentsResource is the resource SubjectAccessReviews are made against
+const appDeploymentsResource = "appdeployments"
+
+// TokenReviewAuthenticator delegates bearer token checks to the Kubernetes API server, so
+// service account and user tokens of the cluster are accepted as they are
+type TokenReviewAuthenticator struct {
+	Client client.Client
+	// Audiences the token must be issued for; the API server audience when empty
+	Audiences []string
+}
+
+// Authenticate implements Authenticator
+func (t *TokenReviewAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: t.Audiences,
+		},
+	}
+	if err := t.Client.Create(r.Context(), review); err != nil {
+		return nil, fmt.Errorf("error reviewing token: %v", err)
+	}
+
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidToken, review.Status.Error)
+		}
+		return nil, ErrInvalidToken
+	}
+
+	user := review.Status.User
+	identity := &Identity{
+		Subject: user.Username,
+		UID:     user.UID,
+		Groups:  user.Groups,
+	}
+	if len(user.Extra) > 0 {
+		identity.Extra = make(map[string][]string, len(user.Extra))
+		for key, values := range user.Extra {
+			identity.Extra[key] = values
+		}
+	}
+	return identity, nil
+}
+
+// SubjectAccessReviewAuthorizer delegates authorization to Kubernetes RBAC: the caller may
+// perform an operation on an app when it may perform the same verb on the AppDeployment
+type SubjectAccessReviewAuthorizer struct {
+	Client client.Client
+}
+
+// Authorize implements Authorizer
+func (s *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, identity *Identity, attributes Attributes) (Decision, error) {
+	resource := &authorizationv1.ResourceAttributes{
+		Namespace: attributes.Namespace,
+		Verb:      attributes.Verb,
+		Group:     deskreev1.GroupVersion.Group,
+		Version:   deskreev1.GroupVersion.Version,
+		Resource:  appDeploymentsResource,
+		Name:      attributes.Name,
+	}
+	if attributes.Resource != "" {
+		// The pods of the app are not known by name up front, so access to all of them is needed
+		resource = &authorizationv1.ResourceAttributes{
+			Namespace:   attributes.Namespace,
+			Verb:        attributes.Verb,
+			Version:     corev1.SchemeGroupVersion.Version,
+			Resource:    attributes.Resource,
+			Subresource: attributes.Subresource,
+		}
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               identity.Subject,
+			UID:                identity.UID,
+			Groups:             identity.Groups,
+			ResourceAttributes: resource,
+		},
+	}
+	if len(identity.Extra) > 0 {
+		review.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(identity.Extra))
+		for key, values := range identity.Extra {
+			review.Spec.Extra[key] = values
+		}
+	}
+
+	if err := s.Client.Create(ctx, review); err != nil {
+		return Decision{}, fmt.Errorf("error reviewing access: %v", err)
+	}
+
+	if review.Status.Allowed {
+		return Decision{Allowed: true}, nil
+	}
+
+	reason := review.Status.Reason
+	if reason == "" {
+		resourceName := resource.Resource
+		if resource.Subresource != "" {
+			resourceName += "/" + resource.Subresource
+		}
+		reason = fmt.Sprintf("user %q cannot %s %s in namespace %q", identity.Subject, attributes.Verb, resourceName, attributes.Namespace)
+	}
+	if review.Status.EvaluationError != "" {
+		apiLog.Info("SubjectAccessReview evaluation error", "user", identity.Subject, "error", review.Status.EvaluationError)
+	}
+	return Decision{Reason: reason}, nil
+}
+
+// ImpersonatingClient returns a function building clients that act as the caller, so the
+// Kubernetes API server applies the caller's own RBAC and audit trail to every change.
+// base lends its scheme and REST mapper to the built clients. All of them share one HTTP
+// transport, so connections to the API server are reused across requests.
+func ImpersonatingClient(cfg *rest.Config, base client.Client) (func(identity *Identity) (client.Client, error), error) {
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP client: %v", err)
+	}
+
+	return func(identity *Identity) (client.Client, error) {
+		return client.New(cfg, client.Options{
+			HTTPClient: impersonatingHTTPClient(httpClient, identity),
+			Scheme:     base.Scheme(),
+			Mapper:     base.RESTMapper(),
+		})
+	}, nil
+}
+
+// impersonatingHTTPClient returns a client sending the requests of shared as identity
+func impersonatingHTTPClient(shared *http.Client, identity *Identity) *http.Client {
+	delegate := shared.Transport
+	if delegate == nil {
+		// rest.HTTPClientFor hands out http.DefaultClient for plain configurations
+		delegate = http.DefaultTransport
+	}
+
+	impersonated := *shared
+	impersonated.Transport = transport.NewImpersonatingRoundTripper(transport.ImpersonationConfig{
+		UserName: identity.Subject,
+		UID:      identity.UID,
+		Groups:   identity.Groups,
+		Extra:    identity.Extra,
+	}, delegate)
+	return &impersonated
+}
+
+// ImpersonatingPods returns a function building pod clients that act as the caller, so pod
+// logs are read with the caller's own RBAC. The clients share one HTTP transport.
+func ImpersonatingPods(cfg *rest.Config) (func(identity *Identity) (corev1client.PodsGetter, error), error) {
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP client: %v", err)
+	}
+
+	return func(identity *Identity) (corev1client.PodsGetter, error) {
+		clientset, err := kubernetes.NewForConfigAndClient(cfg, impersonatingHTTPClient(httpClient, identity))
+		if err != nil {
+			return nil, err
+		}
+		return clientset.CoreV1(), nil
+	}, nil
+}
+
+type clientKey struct{}
+
+// impersonate wraps next so that handlers reach the cluster as the caller when the server
+// has an Impersonate function
+func (s *Server) impersonate(next http.Handler) http.Handler {
+	if s.Impersonate == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFrom(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c, err := s.Impersonate(identity)
+		if err != nil {
+			apiLog.Error(err, "Failed to create impersonating client", "user", identity.Subject)
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to impersonate caller: %v", err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, c)))
+	})
+}
+
+// clientFor returns the client to serve a request with: the caller's impersonating client
+// when there is one, the server's own client otherwise
+func (s *Server) clientFor(r *http.Request) client.Client {
+	if c, ok := r.Context().Value(clientKey{}).(client.Client); ok {
+		return c
+	}
+	return s.Client
+}
+
+// readerFor returns the reader to serve the reads of a request with: the caller's
+// impersonating client when there is one, so the cluster applies the caller's own access,
+// the server cache otherwise
+func (s *Server) readerFor(r *http.Request) client.Reader {
+	if c, ok := r.Context().Value(clientKey{}).(client.Client); ok {
+		return c
+	}
+	return s.reader()
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// LoginRequest carries the credentials of a user logging in
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest carries the refresh token of the session to renew
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// WhoAmIResponse describes the caller of a request and the expiry of its token
+type WhoAmIResponse struct {
+	Username  string    `json:"username"`
+	Groups    []string  `json:"groups,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// APIKeyID is set when the caller authenticated with an API key
+	APIKeyID string `json:"apiKeyID,omitempty"`
+}
+
+// TokenResponse hands out an access token and the refresh token to renew it with
+type TokenResponse struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	TokenType    string    `json:"tokenType"`
+	ExpiresIn    int64     `json:"expiresIn"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// HandleLogin exchanges a username and password for an access and a refresh token
+func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.Users == nil || s.Tokens == nil {
+		writeError(w, http.StatusNotImplemented, "Password login is not configured on this server")
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if req.Username == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+
+	identity, err := s.Users.Authenticate(r.Context(), req.Username, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		apiLog.Info("Rejected login", "username", req.Username)
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		apiLog.Error(err, "Failed to check credentials", "username", req.Username)
+		writeError(w, http.StatusInternalServerError, "Failed to check credentials")
+		return
+	}
+
+	sessionID, err := NewSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	identity.SessionID = sessionID
+
+	s.writeTokens(w, *identity)
+}
+
+// HandleRefresh exchanges a refresh token for a new access and refresh token pair, carrying the
+// current groups of the user
+func (s *Server) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.Tokens == nil {
+		writeError(w, http.StatusNotImplemented, "Token refresh is not configured on this server")
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	identity, err := s.Tokens.VerifyRefresh(req.RefreshToken)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, fmt.Sprintf("Unauthorized: %v", err))
+		return
+	}
+
+	// The user may have been removed or moved to other groups since logging in
+	if s.Users != nil {
+		user, err := s.Users.Lookup(r.Context(), identity.Subject)
+		if errors.Is(err, ErrUserNotFound) {
+			apiLog.Info("Rejected refresh of a removed user", "username", identity.Subject)
+			writeError(w, http.StatusUnauthorized, fmt.Sprintf("Unauthorized: user %q no longer exists", identity.Subject))
+			return
+		}
+		if err != nil {
+			apiLog.Error(err, "Failed to look up user", "username", identity.Subject)
+			writeError(w, http.StatusInternalServerError, "Failed to look up user")
+			return
+		}
+		identity.Groups = user.Groups
+	}
+
+	// A refresh token is good for one refresh: its session ends and the new pair starts another,
+	// so a stolen refresh token stops working once either party uses it
+	if identity.SessionID != "" {
+		s.Tokens.Revoke(identity.SessionID)
+	}
+	sessionID, err := NewSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeTokens(w, Identity{Subject: identity.Subject, Groups: identity.Groups, SessionID: sessionID})
+}
+
+// HandleLogout revokes the session of the caller, invalidating its access and refresh tokens
+func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFrom(r.Context())
+	if !ok || identity.SessionID == "" || s.Tokens == nil {
+		writeError(w, http.StatusBadRequest, "The request is not tied to a session")
+		return
+	}
+
+	s.Tokens.Revoke(identity.SessionID)
+	apiLog.Info("Session revoked", "username", identity.Subject)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": "Logged out",
+	})
+}
+
+// HandleWhoAmI returns the identity of the caller
+func (s *Server) HandleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	identity, ok := IdentityFrom(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized: request is not authenticated")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, WhoAmIResponse{
+		Username:  identity.Subject,
+		Groups:    identity.Groups,
+		ExpiresAt: identity.ExpiresAt,
+		APIKeyID:  identity.APIKeyID,
+	})
+}
+
+// writeTokens issues a new access and refresh token pair for the session of identity
+func (s *Server) writeTokens(w http.ResponseWriter, identity Identity) {
+	accessToken, expiresAt, err := s.Tokens.Issue(identity)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to issue token: %v", err))
+		return
+	}
+
+	refreshToken, _, err := s.Tokens.IssueRefresh(identity)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to issue token: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(expiresAt).Seconds()),
+		ExpiresAt:    expiresAt,
+	})
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxLogStreams bounds how many pod containers one logs request reads at once
+const maxLogStreams = 32
+
+// maxLogLineSize is the longest log line passed on, longer lines are cut
+const maxLogLineSize = 256 * 1024
+
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+// LogLine is one line of the logs of an app, streamed as newline-delimited JSON
+type LogLine struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	// Time the line was written, as recorded by the container runtime
+	Time *metav1.Time `json:"time,omitempty"`
+	Line string       `json:"line,omitempty"`
+	// Error reports a container whose logs could not be read; its stream ends there
+	Error string `json:"error,omitempty"`
+}
+
+// HandleAppLogs streams the logs of every pod matching the selector of an app as
+// newline-delimited JSON. The query parameters follow those of kubectl logs: follow, since
+// (a duration), tail (a line count), container and previous. Lines of different pods are
+// interleaved as they are read. Pods started after the request are not picked up.
+func (s *Server) HandleAppLogs(w http.ResponseWriter, r *http.Request) {
+	if s.Pods == nil {
+		writeError(w, http.StatusServiceUnavailable, "Reading logs is not available on this server")
+		return
+	}
+
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+	options, err := podLogOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	app, err := s.getAppDeployment(r.Context(), namespace, name)
+	if err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	}
+	if app.Spec.Selector == nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("AppDeployment %s has no selector to find its pods by", name))
+		return
+	}
+	selector, err := metav1.LabelSelectorAsSelector(app.Spec.Selector)
+	if err != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("AppDeployment %s has an invalid selector: %v", name, err))
+		return
+	}
+
+	podsGetter, err := s.podsFor(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to impersonate caller: %v", err))
+		return
+	}
+
+	pods := &corev1.PodList{}
+	if err := s.readerFor(r).List(r.Context(), pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		writeAPIError(w, err, "Failed to list pods")
+		return
+	}
+
+	type source struct{ pod, container string }
+	var sources []source
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			if options.Container == "" || options.Container == container.Name {
+				sources = append(sources, source{pod: pod.Name, container: container.Name})
+			}
+		}
+	}
+	if len(sources) > maxLogStreams {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("App %s has %d containers to read logs from, more than %d; pick one with the container parameter",
+			name, len(sources), maxLogStreams))
+		return
+	}
+
+	ctx, cancel := s.streamContext(r)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	lines := make(chan LogLine)
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			containerOptions := *options
+			containerOptions.Container = src.container
+			s.streamContainerLogs(ctx, podsGetter, namespace, src.pod, &containerOptions, lines)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	encoder := json.NewEncoder(w)
+	for line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			cancel()
+			continue
+		}
+		if err := rc.Flush(); err != nil {
+			cancel()
+		}
+	}
+}
+
+// podsFor returns the pod client to read the logs of a request with: one acting as the caller
+// when the server impersonates callers, the server's own otherwise
+func (s *Server) podsFor(r *http.Request) (corev1client.PodsGetter, error) {
+	if s.ImpersonatePods != nil {
+		if identity, ok := IdentityFrom(r.Context()); ok {
+			return s.ImpersonatePods(identity)
+		}
+	}
+	return s.Pods, nil
+}
+
+// streamContainerLogs sends the log lines of one container until its stream ends or ctx is done
+func (s *Server) streamContainerLogs(ctx context.Context, pods corev1client.PodsGetter, namespace, pod string, options *corev1.PodLogOptions, lines chan<- LogLine) {
+	send := func(line LogLine) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	stream, err := pods.Pods(namespace).GetLogs(pod, options).Stream(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			send(LogLine{Pod: pod, Container: options.Container, Error: err.Error()})
+		}
+		return
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		line := LogLine{Pod: pod, Container: options.Container, Line: scanner.Text()}
+		// With timestamps requested, each line starts with its RFC 3339 time
+		if timestamp, text, found := strings.Cut(line.Line, " "); found {
+			if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+				line.Time = &metav1.Time{Time: t}
+				line.Line = text
+			}
+		}
+		if !send(line) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		send(LogLine{Pod: pod, Container: options.Container, Error: err.Error()})
+	}
+}
+
+// podLogOptions reads the log options of a logs request
+func podLogOptions(r *http.Request) (*corev1.PodLogOptions, error) {
+	query := r.URL.Query()
+	options := &corev1.PodLogOptions{
+		Container:  query.Get("container"),
+		Timestamps: true,
+	}
+
+	var err error
+	if value := query.Get("follow"); value != "" {
+		if options.Follow, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("Invalid follow %q: %v", value, err)
+		}
+	}
+	if value := query.Get("previous"); value != "" {
+		if options.Previous, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("Invalid previous %q: %v", value, err)
+		}
+	}
+	if value := query.Get("since"); value != "" {
+		since, err := time.ParseDuration(value)
+		if err != nil || since <= 0 {
+			return nil, fmt.Errorf("Invalid since %q, expected a positive duration such as 10m", value)
+		}
+		seconds := int64((since + time.Second - 1) / time.Second)
+		options.SinceSeconds = &seconds
+	}
+	if value := query.Get("tail"); value != "" {
+		tail, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || tail < 0 {
+			return nil, fmt.Errorf("Invalid tail %q, expected a line count", value)
+		}
+		options.TailLines = &tail
+	}
+
+	return options, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/oidc"
+)
+
+// DefaultOIDCUsernamePrefix keeps OIDC user names apart from local users and API key owners
+const DefaultOIDCUsernamePrefix = "oidc:"
+
+// DefaultOIDCGroupsPrefix keeps the groups of the provider apart from local and Kubernetes
+// groups, so that a provider group named system:masters grants nothing
+const DefaultOIDCGroupsPrefix = "oidc:"
+
+// OIDCAuthenticator accepts ID tokens issued by an external OpenID provider
+type OIDCAuthenticator struct {
+	Verifier *oidc.Verifier
+	// UsernameClaim names the claim used as the caller name, "email" when empty
+	UsernameClaim string
+	// UsernamePrefix is prepended to the caller name, DefaultOIDCUsernamePrefix when empty and
+	// nothing when "-"
+	UsernamePrefix string
+	// GroupsClaim names the claim listing the caller groups, "groups" when empty
+	GroupsClaim string
+	// GroupsPrefix is prepended to every group, DefaultOIDCGroupsPrefix when empty and nothing
+	// when "-"
+	GroupsPrefix string
+}
+
+// Authenticate implements Authenticator
+func (o *OIDCAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	rawToken, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := o.Verifier.Verify(r.Context(), rawToken)
+	if errors.Is(err, oidc.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	usernameClaim := o.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = "email"
+	}
+	groupsClaim := o.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	username := token.StringClaim(usernameClaim)
+	if username == "" {
+		return nil, fmt.Errorf("%w: ID token has no %q claim", ErrInvalidToken, usernameClaim)
+	}
+	// Anyone can put an address they do not own on an account, so it only names them once the
+	// provider checked it
+	if usernameClaim == "email" && !token.BoolClaim("email_verified") {
+		return nil, fmt.Errorf("%w: email %q of the ID token is not verified", ErrInvalidToken, username)
+	}
+
+	groups := token.StringsClaim(groupsClaim)
+	groupsPrefix := claimPrefix(o.GroupsPrefix, DefaultOIDCGroupsPrefix)
+	for i := range groups {
+		groups[i] = groupsPrefix + groups[i]
+	}
+
+	return &Identity{
+		Subject:   claimPrefix(o.UsernamePrefix, DefaultOIDCUsernamePrefix) + username,
+		Groups:    groups,
+		ExpiresAt: token.Expiry,
+	}, nil
+}
+
+// claimPrefix resolves a configured prefix: defaultPrefix when empty and nothing when "-"
+func claimPrefix(prefix, defaultPrefix string) string {
+	switch prefix {
+	case "":
+		return defaultPrefix
+	case "-":
+		return ""
+	default:
+		return prefix
+	}
+}
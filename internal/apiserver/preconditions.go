@@ -0,0 +1,106 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// An AppDeployment's entity tag is its quoted resourceVersion. Reads return it as ETag and
+// writes may send it back in If-Match, so that a write based on a stale read fails with
+// 412 Precondition Failed instead of overwriting a concurrent change.
+
+// etag returns the entity tag of an AppDeployment
+func etag(app *deskreev1.AppDeployment) string {
+	return `"` + app.ResourceVersion + `"`
+}
+
+// setETag sets the ETag header of a response about app
+func setETag(w http.ResponseWriter, app *deskreev1.AppDeployment) {
+	if app.ResourceVersion != "" {
+		w.Header().Set("ETag", etag(app))
+	}
+}
+
+// ifMatches reports whether the If-Match header of a request, when there is one, matches app:
+// "*" matches any existing app, otherwise one of the listed entity tags must be the app's.
+// Weak tags never match, as If-Match uses the strong comparison.
+func ifMatches(r *http.Request, app *deskreev1.AppDeployment) bool {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || tag == etag(app) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkIfMatch answers 412 Precondition Failed and returns false when the If-Match header of
+// a request does not match app
+func checkIfMatch(w http.ResponseWriter, r *http.Request, app *deskreev1.AppDeployment) bool {
+	if ifMatches(r, app) {
+		return true
+	}
+	w.Header().Set("ETag", etag(app))
+	writeError(w, http.StatusPreconditionFailed, fmt.Sprintf("AppDeployment %s has changed: If-Match %s does not match its ETag %s",
+		app.Name, strings.Join(r.Header.Values("If-Match"), ", "), etag(app)))
+	return false
+}
+
+// latestReaderFor returns the reader that writes of a request are based on. Unlike the cache,
+// it returns the latest version of an app, so that a client writing right after a read or
+// write does not fail If-Match against a stale copy.
+func (s *Server) latestReaderFor(r *http.Request) client.Reader {
+	if c, ok := r.Context().Value(clientKey{}).(client.Client); ok {
+		return c
+	}
+	if s.APIReader != nil {
+		return s.APIReader
+	}
+	return s.Client
+}
+
+// deleteOptions returns the options of a delete request. With If-Match, the app is read and
+// checked first, and the delete is made conditional on the resourceVersion read so that a
+// change in between fails with a conflict. It returns false when the response has been sent.
+func (s *Server) deleteOptions(w http.ResponseWriter, r *http.Request, namespace, name string) ([]client.DeleteOption, bool) {
+	if len(r.Header.Values("If-Match")) == 0 {
+		return nil, true
+	}
+
+	app := &deskreev1.AppDeployment{}
+	if err := s.latestReaderFor(r).Get(r.Context(), types.NamespacedName{Name: name, Namespace: namespace}, app); err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return nil, false
+	}
+	if !checkIfMatch(w, r, app) {
+		return nil, false
+	}
+	return []client.DeleteOption{client.Preconditions{ResourceVersion: &app.ResourceVersion}}, true
+}
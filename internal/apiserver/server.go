@@ -20,31 +20,94 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+	"github.com/espinozasenior/go-assesstment.git/internal/controller"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 var (
 	apiLog = ctrl.Log.WithName("apiserver")
 )
 
+// DefaultNamespace is used when a request does not name a namespace
+const DefaultNamespace = "default"
+
+// DefaultShutdownTimeout bounds how long in-flight requests may take to finish on shutdown,
+// kept below the 30s default graceful shutdown timeout of the manager
+const DefaultShutdownTimeout = 20 * time.Second
+
+// maxRequestBodyBytes bounds the request bodies the server reads
+const maxRequestBodyBytes = 1 << 20
+
+// readyCheckTimeout bounds how long a readiness check waits for the cache to sync
+const readyCheckTimeout = time.Second
+
 type Server struct {
-	Client  client.Client
-	watcher watch.Interface
-	stopCh  chan struct{}
-	// Cache to store AppDeployment status information
-	DeploymentCache map[string]*deskreev1.AppDeployment
+	// Client writes to the cluster and reads what Cache does not hold
+	Client client.Client
+	// Cache serves AppDeployment reads from a shared informer, which keeps itself current from
+	// its watch and relists when the watch expires; reads go to Client when nil
+	Cache client.Reader
+	// Informers notifies the server of AppDeployment changes for watches and tells whether the
+	// cache has synced; watches are unavailable when nil
+	Informers cache.Informers
+	// Addr is the address Start listens on
+	Addr string
+	// ShutdownTimeout bounds the draining of in-flight requests, DefaultShutdownTimeout when zero
+	ShutdownTimeout time.Duration
+	// listening is set while Start accepts connections
+	listening atomic.Bool
+
+	watchOnce sync.Once
+	watches   *watchHub
+	// streamsClosed is closed when shutdown starts, ending the watch and log streams that
+	// would otherwise hold it up for the whole ShutdownTimeout
+	streamsMu     sync.Mutex
+	streamsClosed chan struct{}
+	// Tokens signs the access tokens the server hands out
+	Tokens *TokenIssuer
+	// Authenticator checks the bearer token of every request; requests are not authenticated when nil
+	Authenticator Authenticator
+	// Users checks the credentials of POST /auth/login; password login is disabled when nil
+	Users UserStore
+	// Authorizer decides which apps a caller may read or change; every authenticated caller
+	// may do anything when nil
+	Authorizer Authorizer
+	// Impersonate builds a client acting as the caller for the handlers to reach the cluster
+	// with; the server's own Client is used when nil
+	Impersonate func(identity *Identity) (client.Client, error)
+	// APIKeys holds the API keys managed under /api/v1/apikeys; API keys are disabled when nil
+	APIKeys APIKeyStore
+	// APIKeyMaxTTL bounds how far in the future an API key may expire, DefaultAPIKeyMaxTTL when zero
+	APIKeyMaxTTL time.Duration
+	// Pods reads the logs of the pods of an app; log streaming is unavailable when nil
+	Pods corev1client.PodsGetter
+	// ImpersonatePods builds a pod client acting as the caller to read logs with instead of Pods
+	ImpersonatePods func(identity *Identity) (corev1client.PodsGetter, error)
+	// APIReader reads what the cache does not hold, such as Events, straight from the API
+	// server; the events endpoint is unavailable when nil
+	APIReader client.Reader
+	// Idempotency remembers the outcome of create requests sent with an Idempotency-Key
+	// header; the header is ignored when nil
+	Idempotency *IdempotencyStore
 }
 
 type DeployRequest struct {
@@ -53,233 +116,383 @@ type DeployRequest struct {
 	MemoryLimit string `json:"memoryLimit"`
 	MinReplicas int32  `json:"minReplicas"`
 	MaxReplicas int32  `json:"maxReplicas"`
+	// ConfigMaps and Secrets are exposed to the container as environment variables
+	ConfigMaps []string `json:"configMaps,omitempty"`
+	Secrets    []string `json:"secrets,omitempty"`
+}
+
+// ImportRequest asks for an existing Deployment to be adopted by a new AppDeployment
+type ImportRequest struct {
+	// Name of the AppDeployment to create, defaults to the Deployment name
+	Name string `json:"name"`
+	// Deployment is the name of the existing Deployment to take over
+	Deployment string `json:"deployment"`
+}
+
+// DriftResponse reports the fields of the generated resources changed outside the controller
+type DriftResponse struct {
+	Name    string                `json:"name"`
+	Policy  string                `json:"policy"`
+	Drifted bool                  `json:"drifted"`
+	Fields  []deskreev1.FieldDiff `json:"fields,omitempty"`
 }
 
 type StatusResponse struct {
 	Status   string `json:"status"`
 	Replicas int32  `json:"replicas"`
+	Message  string `json:"message,omitempty"`
 }
 
-func NewServer() (*Server, error) {
-	cfg, err := config.GetConfig()
-	if err != nil {
-		return nil, fmt.Errorf("error getting kubeconfig: %v", err)
+// RequestNamespace returns the namespace named by the request path or, for the original
+// endpoints, its "namespace" query parameter, falling back to DefaultNamespace
+func RequestNamespace(r *http.Request) string {
+	if namespace := r.PathValue("namespace"); namespace != "" {
+		return namespace
 	}
-
-	scheme := scheme.Scheme
-	if err := deskreev1.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("error adding deskree scheme: %v", err)
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		return namespace
 	}
+	return DefaultNamespace
+}
 
-	client, err := client.New(cfg, client.Options{Scheme: scheme})
+// NewServer creates an API server listening on addr that shares the client and cache of mgr
+// and signs and verifies access tokens with signingKey. Add it to mgr to run it.
+func NewServer(mgr manager.Manager, addr string, signingKey []byte) (*Server, error) {
+	tokens, err := NewTokenIssuer(signingKey, DefaultTokenTTL)
 	if err != nil {
-		return nil, fmt.Errorf("error creating client: %v", err)
+		return nil, err
 	}
 
-	server := &Server{
-		Client:          client,
-		stopCh:          make(chan struct{}),
-		DeploymentCache: make(map[string]*deskreev1.AppDeployment),
+	// Register the AppDeployment informer up front so the manager syncs it before serving
+	if _, err := mgr.GetCache().GetInformer(context.Background(), &deskreev1.AppDeployment{}); err != nil {
+		return nil, fmt.Errorf("error creating AppDeployment informer: %v", err)
 	}
 
-	// Setup the watcher for AppDeployment CRDs
-	if err := server.setupWatcher(); err != nil {
-		return nil, fmt.Errorf("error setting up watcher: %v", err)
-	}
-
-	return server, nil
+	// Pod logs are a subresource stream the controller-runtime client cannot read
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kubernetes clientset: %v", err)
+	}
+
+	return &Server{
+		Client:        mgr.GetClient(),
+		Cache:         mgr.GetCache(),
+		Informers:     mgr.GetCache(),
+		Addr:          addr,
+		Tokens:        tokens,
+		Authenticator: tokens,
+		Pods:          clientset.CoreV1(),
+		APIReader:     mgr.GetAPIReader(),
+		Idempotency:   &IdempotencyStore{},
+	}, nil
 }
 
-func (s *Server) Start(port int) error {
-	http.HandleFunc("/deploy", s.HandleDeploy)
-	http.HandleFunc("/status/", s.HandleStatus)
-	http.HandleFunc("/", s.HandleDelete)
+// Handler returns the router serving the REST API
+func (s *Server) Handler() http.Handler {
+	s.startWatchHub()
+	mux := http.NewServeMux()
+
+	// Versioned resource-oriented API
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps", s.authorize(VerbList, s.HandleListApps))
+	mux.HandleFunc("POST /api/v1/namespaces/{namespace}/apps", s.authorize(VerbCreate, s.idempotent(s.HandleCreateApp)))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps/{name}", s.authorize(VerbGet, s.HandleGetApp))
+	mux.HandleFunc("PUT /api/v1/namespaces/{namespace}/apps/{name}", s.authorize(VerbUpdate, s.HandleUpdateApp))
+	mux.HandleFunc("PATCH /api/v1/namespaces/{namespace}/apps/{name}", s.authorize(VerbPatch, s.HandlePatchApp))
+	mux.HandleFunc("DELETE /api/v1/namespaces/{namespace}/apps/{name}", s.authorize(VerbDelete, s.HandleDeleteApp))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps/{name}/drift", s.authorize(VerbGet, s.HandleDrift))
+	mux.HandleFunc("POST /api/v1/namespaces/{namespace}/apps/{name}/diff", s.authorize(VerbGet, s.HandleDiffApp))
+	mux.HandleFunc("POST /api/v1/apps/{name}/diff", s.authorize(VerbGet, s.HandleDiffApp))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps/{name}/watch", s.authorize(VerbWatch, s.HandleWatchApp))
+	mux.HandleFunc("GET /api/v1/apps/{name}/watch", s.authorize(VerbWatch, s.HandleWatchApp))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps/{name}/logs", s.authorize(VerbGet, s.authorizeLogs(s.HandleAppLogs)))
+	mux.HandleFunc("GET /api/v1/apps/{name}/logs", s.authorize(VerbGet, s.authorizeLogs(s.HandleAppLogs)))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/apps/{name}/events", s.authorize(VerbGet, s.HandleAppEvents))
+	mux.HandleFunc("GET /api/v1/apps/{name}/events", s.authorize(VerbGet, s.HandleAppEvents))
+
+	// API keys of the caller
+	mux.HandleFunc("POST /api/v1/apikeys", s.HandleCreateAPIKey)
+	mux.HandleFunc("GET /api/v1/apikeys", s.HandleListAPIKeys)
+	mux.HandleFunc("DELETE /api/v1/apikeys/{id}", s.HandleRevokeAPIKey)
+
+	// Session of the caller
+	mux.HandleFunc("POST /auth/logout", s.HandleLogout)
+	mux.HandleFunc("GET /auth/whoami", s.HandleWhoAmI)
+
+	// Original endpoints, kept for existing clients
+	mux.HandleFunc("POST /deploy", s.authorize(VerbCreate, s.idempotent(s.HandleDeploy)))
+	mux.HandleFunc("POST /import", s.authorize(VerbCreate, s.HandleImport))
+	mux.HandleFunc("GET /status/{name}", s.authorize(VerbGet, s.HandleStatus))
+	mux.HandleFunc("GET /apps/{name}/drift", s.authorize(VerbGet, s.HandleDrift))
+	mux.HandleFunc("DELETE /{name}", s.authorize(VerbDelete, s.HandleDelete))
+
+	protected := s.impersonate(withErrorResponses(mux))
+	if s.Authenticator != nil {
+		protected = Authenticate(s.Authenticator, protected)
+	}
+
+	// Login and refresh are the only endpoints reachable without an access token
+	public := http.NewServeMux()
+	public.HandleFunc("POST /auth/login", s.HandleLogin)
+	public.HandleFunc("POST /auth/refresh", s.HandleRefresh)
+	public.Handle("/", protected)
+	return limitRequestBody(public)
+}
 
-	addr := fmt.Sprintf(":%d", port)
-	apiLog.Info("Server starting", "address", addr)
-	return http.ListenAndServe(addr, nil)
+// limitRequestBody fails the reads of a request body beyond maxRequestBodyBytes
+func limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
-// setupWatcher creates a watch.Interface to monitor AppDeployment CRD changes
-func (s *Server) setupWatcher() error {
-	// Create a dynamic client to watch for AppDeployment resources
-	cfg, err := config.GetConfig()
+// Start implements manager.Runnable: it serves the REST API until ctx is cancelled, then
+// stops accepting connections and lets in-flight requests finish within ShutdownTimeout
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
 	if err != nil {
-		return fmt.Errorf("error getting kubeconfig: %v", err)
+		return fmt.Errorf("error listening on %s: %v", s.Addr, err)
 	}
 
-	// Create a dynamic client
-	dynamicClient, err := dynamic.NewForConfig(cfg)
-	if err != nil {
-		return fmt.Errorf("error creating dynamic client: %v", err)
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
 	}
+	server.RegisterOnShutdown(s.closeStreams)
 
-	// Define the GVR (GroupVersionResource) for AppDeployment
-	appDeploymentGVR := schema.GroupVersionResource{
-		Group:    "deskree.platform.deskree.com",
-		Version:  "v1",
-		Resource: "appdeployments",
-	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	s.listening.Store(true)
+	defer s.listening.Store(false)
+	apiLog.Info("Server started", "address", listener.Addr().String())
 
-	// Create a watcher for AppDeployment resources
-	watcher, err := dynamicClient.Resource(appDeploymentGVR).Namespace("").Watch(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("error creating watcher: %v", err)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
 	}
 
-	s.watcher = watcher
-
-	// Start a goroutine to handle watch events
-	go s.watchEvents()
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	apiLog.Info("Shutting down, draining in-flight requests", "timeout", timeout)
+	s.listening.Store(false)
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down API server: %v", err)
+	}
 	return nil
 }
 
-// watchEvents processes events from the watcher
-func (s *Server) watchEvents() {
-	apiLog.Info("Starting to watch AppDeployment CRD changes")
-	defer apiLog.Info("Stopped watching AppDeployment CRD changes")
+// streamsDone returns a channel closed once the server starts shutting down
+func (s *Server) streamsDone() <-chan struct{} {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	if s.streamsClosed == nil {
+		s.streamsClosed = make(chan struct{})
+	}
+	return s.streamsClosed
+}
 
-	for {
+// closeStreams ends the open watch and log streams
+func (s *Server) closeStreams() {
+	done := s.streamsDone()
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	select {
+	case <-done:
+	default:
+		close(s.streamsClosed)
+	}
+}
+
+// streamContext returns the context of a long-lived response, cancelled when the client goes
+// away or the server shuts down
+func (s *Server) streamContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	go func() {
 		select {
-		case event, ok := <-s.watcher.ResultChan():
-			if !ok {
-				apiLog.Info("Watcher channel closed, restarting watcher...")
-				// Try to restart the watcher
-				if err := s.setupWatcher(); err != nil {
-					apiLog.Error(err, "Failed to restart watcher")
-					return
-				}
-				continue
-			}
-
-			// Process the event based on its type
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				// Convert the unstructured object to AppDeployment
-				unstructured, ok := event.Object.(*metav1.PartialObjectMetadata)
-				if !ok {
-					apiLog.Info("Unexpected object type", "type", fmt.Sprintf("%T", event.Object))
-					continue
-				}
-
-				// Get the AppDeployment from the API server to ensure we have the latest state
-				appDeployment := &deskreev1.AppDeployment{}
-				err := s.Client.Get(context.Background(), types.NamespacedName{Name: unstructured.GetName(), Namespace: unstructured.GetNamespace()}, appDeployment)
-				if err != nil {
-					apiLog.Error(err, "Error getting AppDeployment", "namespace", unstructured.GetNamespace(), "name", unstructured.GetName())
-					continue
-				}
-
-				// Store the AppDeployment in the cache
-				s.DeploymentCache[appDeployment.Name] = appDeployment
-				apiLog.Info("AppDeployment updated in cache",
-					"name", appDeployment.Name,
-					"state", appDeployment.Status.State,
-					"replicas", appDeployment.Status.AvailableReplicas)
-
-			case watch.Deleted:
-				unstructured, ok := event.Object.(*metav1.PartialObjectMetadata)
-				if !ok {
-					apiLog.Info("Unexpected object type", "type", fmt.Sprintf("%T", event.Object))
-					continue
-				}
-
-				// Remove the AppDeployment from the cache
-				delete(s.DeploymentCache, unstructured.GetName())
-				apiLog.Info("AppDeployment removed from cache", "name", unstructured.GetName())
-
-			case watch.Error:
-				apiLog.Error(nil, "Error event received", "object", event.Object)
-			}
-		case <-s.stopCh:
-			return
+		case <-s.streamsDone():
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+	return ctx, cancel
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: every replica serves the API
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// ReadyCheck is a healthz.Checker reporting ready once the cache has synced and the server
+// is accepting connections
+func (s *Server) ReadyCheck(r *http.Request) error {
+	if s.Informers != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+		defer cancel()
+		if !s.Informers.WaitForCacheSync(ctx) {
+			return fmt.Errorf("cache not synced")
+		}
+	}
+	if !s.listening.Load() {
+		return fmt.Errorf("API server not listening")
+	}
+	return nil
+}
+
+// reader returns where AppDeployments are read from
+func (s *Server) reader() client.Reader {
+	if s.Cache != nil {
+		return s.Cache
 	}
+	return s.Client
 }
 
 func (s *Server) HandleDeploy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req DeployRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if fields := prepareDeployRequest(&req); len(fields) > 0 {
+		writeFieldErrors(w, "Invalid deploy request", fields)
+		return
+	}
+
+	opts, err := createOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dryRun := len(opts) > 0
+
+	appDeployment := newAppDeployment(RequestNamespace(r), req)
+	setCreator(r, appDeployment)
+
+	// Deploying an existing app updates it, so that a retried deploy does not fail
+	code, outcome := http.StatusCreated, "created"
+	err = s.clientFor(r).Create(r.Context(), appDeployment, opts...)
+	if apierrors.IsAlreadyExists(err) {
+		if !s.checkAccess(w, r, VerbUpdate, req.Name) {
+			return
+		}
+		code, outcome = http.StatusOK, "updated"
+		var changed bool
+		if changed, err = s.deployExisting(r, req, dryRun); err == nil && !changed {
+			outcome = "unchanged"
+		}
+	}
+	if err != nil {
+		writeAPIError(w, err, "Failed to deploy AppDeployment")
+		return
+	}
+
+	message := fmt.Sprintf("Deployment CRD %s %s", req.Name, outcome)
+	if dryRun {
+		message = fmt.Sprintf("Deployment CRD %s would be %s (dry run, nothing was changed)", req.Name, outcome)
+	}
+	writeJSON(w, code, map[string]string{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// deployExisting writes a deploy request onto the existing AppDeployment of the same name,
+// retrying on conflicts with concurrent writers. It reports whether the spec changed; an app
+// being deleted is a conflict, resolved by retrying the deploy once it is gone.
+func (s *Server) deployExisting(r *http.Request, req DeployRequest, dryRun bool) (bool, error) {
+	var opts []client.UpdateOption
+	if dryRun {
+		opts = append(opts, client.DryRunAll)
+	}
+
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		appDeployment := &deskreev1.AppDeployment{}
+		key := types.NamespacedName{Name: req.Name, Namespace: RequestNamespace(r)}
+		if err := s.latestReaderFor(r).Get(r.Context(), key, appDeployment); err != nil {
+			return err
+		}
+		if !appDeployment.DeletionTimestamp.IsZero() {
+			return apierrors.NewConflict(deskreev1.GroupVersion.WithResource("appdeployments").GroupResource(), req.Name,
+				fmt.Errorf("the AppDeployment is being deleted, retry once it is gone"))
+		}
+		// The app may have been replaced since the caller's access was checked
+		if err := s.authorizeApp(r, VerbUpdate, appDeployment); err != nil {
+			return err
+		}
+
+		current := appDeployment.Spec.DeepCopy()
+		setAppDeploymentSpec(&appDeployment.Spec, req)
+		if changed = !equality.Semantic.DeepEqual(current, &appDeployment.Spec); !changed {
+			return nil
+		}
+		return s.clientFor(r).Update(r.Context(), appDeployment, opts...)
+	})
+	return changed, err
+}
+
+func (s *Server) HandleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	if req.Name == "" || req.Image == "" || req.MemoryLimit == "" {
-		http.Error(w, "Name, image, and memoryLimit are required", http.StatusBadRequest)
+	var req ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
 
-	if req.MinReplicas <= 0 {
-		req.MinReplicas = 1
+	if fields := validateImportRequest(req); len(fields) > 0 {
+		writeFieldErrors(w, "Invalid import request", fields)
+		return
 	}
 
-	if req.MaxReplicas < req.MinReplicas {
-		req.MaxReplicas = req.MinReplicas
+	if req.Name == "" {
+		req.Name = req.Deployment
 	}
 
+	// The controller back-fills the spec from the adopted Deployment
 	appDeployment := &deskreev1.AppDeployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Name,
-			Namespace: "default",
+			Namespace: RequestNamespace(r),
 			Labels: map[string]string{
 				"app.kubernetes.io/name":       req.Name,
 				"app.kubernetes.io/managed-by": "go-assessment-api",
 			},
 		},
 		Spec: deskreev1.AppDeploymentSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": req.Name,
-				},
-			},
-			MemoryLimit: req.MemoryLimit,
-			MinReplicas: req.MinReplicas,
-			MaxReplicas: req.MaxReplicas,
-			Template: deskreev1.PodTemplateSpec{
-				ObjectMeta: deskreev1.ObjectMeta{
-					Labels: map[string]string{
-						"app": req.Name,
-					},
-				},
-				Spec: deskreev1.PodSpec{
-					Containers: []deskreev1.Container{
-						{
-							Name:  req.Name,
-							Image: req.Image,
-							Ports: []deskreev1.ContainerPort{
-								{
-									ContainerPort: 80,
-								},
-							},
-						},
-					},
-				},
-			},
+			AppName: req.Deployment,
+			Adopt:   true,
 		},
 	}
+	setCreator(r, appDeployment)
 
-	if err := s.Client.Create(context.Background(), appDeployment); err != nil {
-		response := map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to create AppDeployment: %v", err),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			apiLog.Error(err, "Failed to encode error response")
-		}
+	if err := s.clientFor(r).Create(r.Context(), appDeployment); err != nil {
+		writeAPIError(w, err, "Failed to create AppDeployment")
 		return
 	}
 
 	response := map[string]string{
 		"status":  "success",
-		"message": fmt.Sprintf("Deployment CRD %s created", req.Name),
+		"message": fmt.Sprintf("Deployment %s is being imported as %s", req.Deployment, req.Name),
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -288,48 +501,49 @@ func (s *Server) HandleDeploy(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getAppDeployment returns the named AppDeployment from the server cache
+func (s *Server) getAppDeployment(ctx context.Context, namespace, name string) (*deskreev1.AppDeployment, error) {
+	appDeployment := &deskreev1.AppDeployment{}
+	if err := s.reader().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, appDeployment); err != nil {
+		return nil, err
+	}
+	return appDeployment, nil
+}
+
 func (s *Server) HandleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 3 {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid path")
 		return
 	}
 
 	name := pathParts[2]
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Name is required")
 		return
 	}
 
-	var appDeployment *deskreev1.AppDeployment
-	var found bool
-
-	// Check the cache first
-	appDeployment, found = s.DeploymentCache[name]
-
-	// If not found in cache, get it from the API server
-	if !found {
-		apiLog.Info("AppDeployment not found in cache, fetching from API server", "name", name)
-		appDeployment = &deskreev1.AppDeployment{}
-		if err := s.Client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "default"}, appDeployment); err != nil {
-			apiLog.Error(err, "Failed to get AppDeployment", "name", name)
-			http.Error(w, fmt.Sprintf("Failed to get AppDeployment: %v", err), http.StatusNotFound)
-			return
-		}
-		// Add to cache for future requests
-		s.DeploymentCache[name] = appDeployment
-	} else {
-		apiLog.Info("AppDeployment found in cache", "name", name)
+	appDeployment, err := s.getAppDeployment(r.Context(), RequestNamespace(r), name)
+	if err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
 	}
 
 	response := StatusResponse{
 		Status:   appDeployment.Status.State,
 		Replicas: appDeployment.Status.AvailableReplicas,
+		Message:  appDeployment.Status.Message,
+	}
+
+	// The controller may not have observed the deletion yet
+	if !appDeployment.DeletionTimestamp.IsZero() && response.Status != controller.StateTerminating {
+		response.Status = controller.StateTerminating
+		response.Message = "Deletion requested, waiting for teardown to start"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -338,44 +552,80 @@ func (s *Server) HandleStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) HandleDrift(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	appDeployment, err := s.getAppDeployment(r.Context(), RequestNamespace(r), name)
+	if err != nil {
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	}
+
+	policy := appDeployment.Spec.DriftPolicy
+	if policy == "" {
+		policy = deskreev1.DriftPolicyReport
+	}
+
+	response := DriftResponse{
+		Name:    appDeployment.Name,
+		Policy:  string(policy),
+		Drifted: meta.IsStatusConditionTrue(appDeployment.Status.Conditions, controller.ConditionDrifted),
+		Fields:  appDeployment.Status.Drift,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Error(err, "Failed to encode drift response")
+	}
+}
+
 func (s *Server) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 2 {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid path")
 		return
 	}
 
 	name := pathParts[1]
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	namespace := RequestNamespace(r)
+	opts, ok := s.deleteOptions(w, r, namespace, name)
+	if !ok {
 		return
 	}
 
 	appDeployment := &deskreev1.AppDeployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: "default",
+			Namespace: namespace,
 		},
 	}
 
-	if err := s.Client.Delete(context.Background(), appDeployment); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete AppDeployment: %v", err), http.StatusInternalServerError)
+	if err := s.clientFor(r).Delete(r.Context(), appDeployment, opts...); err != nil {
+		writeAPIError(w, err, "Failed to delete AppDeployment")
 		return
 	}
 
-	// Remove the deleted AppDeployment from the cache to prevent stale data
-	if _, exists := s.DeploymentCache[name]; exists {
-		delete(s.DeploymentCache, name)
-		apiLog.Info("AppDeployment removed from cache after deletion", "name", name)
-	}
-
 	w.WriteHeader(http.StatusOK)
-	if _, err := fmt.Fprintf(w, "AppDeployment \"%s\" deleted.\n", name); err != nil {
+	if _, err := fmt.Fprintf(w, "AppDeployment \"%s\" deletion started.\n", name); err != nil {
 		apiLog.Error(err, "Failed to write delete response")
 	}
 }
@@ -0,0 +1,287 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+	"github.com/espinozasenior/go-assesstment.git/internal/apiserver"
+	"github.com/espinozasenior/go-assesstment.git/pkg/oidc"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeIssuer is a stand-in OpenID provider supporting discovery, JWKS and the device
+// authorization grant with PKCE
+type fakeIssuer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu        sync.Mutex
+	challenge string
+	polls     int
+}
+
+func newFakeIssuer(t *testing.T, clientID string) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	issuer := &fakeIssuer{key: key, clientID: clientID}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]string{
+			"issuer":                        issuer.URL,
+			"token_endpoint":                issuer.URL + "/token",
+			"device_authorization_endpoint": issuer.URL + "/device",
+			"jwks_uri":                      issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code_challenge_method") != "S256" || r.FormValue("client_id") != clientID {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+			return
+		}
+		issuer.mu.Lock()
+		issuer.challenge = r.FormValue("code_challenge")
+		issuer.mu.Unlock()
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": issuer.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("device_code") != "device-code" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != issuer.challenge {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		// The user approves the request after the first poll
+		issuer.polls++
+		if issuer.polls == 1 {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			return
+		}
+
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": "opaque-access-token",
+			"token_type":   "Bearer",
+			"id_token":     issuer.idToken(t, clientID, time.Hour),
+		})
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// idToken signs an ID token for alice, with a verified email, issued to audience
+func (f *fakeIssuer) idToken(t *testing.T, audience string, ttl time.Duration) string {
+	return f.signIDToken(t, audience, ttl, true)
+}
+
+// signIDToken signs an ID token for alice issued to audience
+func (f *fakeIssuer) signIDToken(t *testing.T, audience string, ttl time.Duration, emailVerified bool) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "test-key"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":            f.URL,
+		"aud":            audience,
+		"sub":            "1234567890",
+		"email":          "alice@example.com",
+		"email_verified": emailVerified,
+		"groups":         []string{"platform", "system:masters"},
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(ttl).Unix(),
+	})
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign ID token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeTestJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestOIDCDeviceLogin(t *testing.T) {
+	issuer := newFakeIssuer(t, "go-assessment-cli")
+	ctx := context.Background()
+
+	provider, err := oidc.Discover(ctx, nil, issuer.URL)
+	if err != nil {
+		t.Fatalf("Failed to discover provider: %v", err)
+	}
+
+	flow := &oidc.DeviceFlow{Provider: provider, ClientID: "go-assessment-cli"}
+	authorization, err := flow.Start(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start device flow: %v", err)
+	}
+	if authorization.UserCode != "ABCD-EFGH" {
+		t.Errorf("Expected user code ABCD-EFGH, got %q", authorization.UserCode)
+	}
+
+	tokens, err := flow.Wait(ctx, authorization)
+	if err != nil {
+		t.Fatalf("Failed to complete device flow: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add v1 scheme: %v", err)
+	}
+
+	serverTokens, err := apiserver.NewTokenIssuer([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	var impersonatedGroups []string
+	server := &apiserver.Server{
+		Client: fakeClient,
+		Tokens: serverTokens,
+		Authenticator: apiserver.Authenticators{
+			serverTokens,
+			&apiserver.OIDCAuthenticator{Verifier: oidc.NewVerifier(provider, "go-assessment-cli", nil)},
+		},
+		Impersonate: func(identity *apiserver.Identity) (client.Client, error) {
+			impersonatedGroups = identity.Groups
+			return fakeClient, nil
+		},
+	}
+	handler := server.Handler()
+
+	whoami := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/auth/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := whoami(tokens.IDToken)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d with the ID token, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	var identity apiserver.WhoAmIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if identity.Username != "oidc:alice@example.com" || strings.Join(identity.Groups, ",") != "oidc:platform,oidc:system:masters" {
+		t.Errorf("Expected oidc:alice@example.com in groups oidc:platform and oidc:system:masters, got %+v", identity)
+	}
+	if slices.Contains(impersonatedGroups, "system:masters") || !slices.Contains(impersonatedGroups, "oidc:system:masters") {
+		t.Errorf("Expected the provider group system:masters to be impersonated with its prefix, got %v", impersonatedGroups)
+	}
+
+	if resp := whoami(issuer.idToken(t, "another-client", time.Hour)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for a token issued to another client, got %d", http.StatusUnauthorized, resp.Code)
+	}
+	if resp := whoami(issuer.idToken(t, "go-assessment-cli", -time.Hour)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for an expired token, got %d", http.StatusUnauthorized, resp.Code)
+	}
+	if resp := whoami(issuer.signIDToken(t, "go-assessment-cli", time.Hour, false)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for a token with an unverified email, got %d", http.StatusUnauthorized, resp.Code)
+	}
+}
+
+// TestOIDCVerifierKeyFetch tests that concurrent verifications share one JWKS fetch and that a
+// failed fetch is not retried before the refresh interval
+func TestOIDCVerifierKeyFetch(t *testing.T) {
+	issuer := newFakeIssuer(t, "go-assessment-cli")
+
+	var mu sync.Mutex
+	fetches := 0
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		writeTestJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "unavailable"})
+	}))
+	defer jwks.Close()
+
+	verifier := oidc.NewVerifier(&oidc.Provider{Issuer: issuer.URL, JWKSURI: jwks.URL}, "go-assessment-cli", nil)
+	token := issuer.idToken(t, "go-assessment-cli", time.Hour)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := verifier.Verify(context.Background(), token); err == nil {
+				t.Error("Expected verification to fail while the JWKS is unavailable")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := verifier.Verify(context.Background(), token); err == nil {
+		t.Error("Expected verification to fail while the JWKS is unavailable")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fetches != 1 {
+		t.Errorf("Expected a single JWKS fetch, got %d", fetches)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user or a wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUserNotFound is returned when looking up a user the store does not hold
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// dummyPasswordHash is compared against when the user does not exist, so that unknown and
+// known users take the same time to reject
+var dummyPasswordHash = []byte("$2a$10$liJYyd9fS2eX5aa0yQA43uTmt.J76K0Zmss3jLVUkbJqgYST.GYUy")
+
+// UserStore checks the credentials of API users
+type UserStore interface {
+	// Authenticate returns the identity of the user when password matches its stored hash,
+	// ErrInvalidCredentials otherwise
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+	// Lookup returns the current identity of a user, ErrUserNotFound when it does not exist
+	Lookup(ctx context.Context, username string) (*Identity, error)
+}
+
+// User is a stored API user
+type User struct {
+	Username string `json:"username"`
+	// PasswordHash is a bcrypt hash of the password, e.g. from `htpasswd -nbBC 10 <user> <password>`
+	PasswordHash string   `json:"passwordHash"`
+	Groups       []string `json:"groups,omitempty"`
+}
+
+// checkPassword compares password against the user hash, or against a dummy hash when the
+// user is nil
+func checkPassword(user *User, password string) (*Identity, error) {
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{Subject: user.Username, Groups: user.Groups}, nil
+}
+
+// FileUserStore holds the users listed in a JSON file
+type FileUserStore struct {
+	users map[string]*User
+}
+
+// LoadFileUserStore reads a JSON array of users from path
+func LoadFileUserStore(path string) (*FileUserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading users file: %v", err)
+	}
+
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("error parsing users file: %v", err)
+	}
+
+	return NewFileUserStore(users)
+}
+
+// NewFileUserStore returns a store holding users
+func NewFileUserStore(users []User) (*FileUserStore, error) {
+	store := &FileUserStore{users: make(map[string]*User, len(users))}
+	for i := range users {
+		user := &users[i]
+		if user.Username == "" || user.PasswordHash == "" {
+			return nil, fmt.Errorf("user %d needs both username and passwordHash", i)
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("user %s: passwordHash is not a bcrypt hash: %v", user.Username, err)
+		}
+		store.users[user.Username] = user
+	}
+	return store, nil
+}
+
+// Authenticate implements UserStore
+func (f *FileUserStore) Authenticate(_ context.Context, username, password string) (*Identity, error) {
+	return checkPassword(f.users[username], password)
+}
+
+// Lookup implements UserStore
+func (f *FileUserStore) Lookup(_ context.Context, username string) (*Identity, error) {
+	user, ok := f.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &Identity{Subject: user.Username, Groups: user.Groups}, nil
+}
+
+// SecretUserStore reads users from a Kubernetes Secret on every login, so users can be
+// added or removed without restarting the server. Each data key is a username and its
+// value the bcrypt hash of the password, optionally followed by ":" and a comma separated
+// list of groups.
+type SecretUserStore struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+}
+
+// Authenticate implements UserStore
+func (s *SecretUserStore) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	user, err := s.user(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		return checkPassword(nil, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkPassword(user, password)
+}
+
+// Lookup implements UserStore
+func (s *SecretUserStore) Lookup(ctx context.Context, username string) (*Identity, error) {
+	user, err := s.user(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Subject: user.Username, Groups: user.Groups}, nil
+}
+
+// user reads a user from the Secret
+func (s *SecretUserStore) user(ctx context.Context, username string) (*User, error) {
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: s.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("error reading users secret %s/%s: %v", s.Namespace, s.Name, err)
+	}
+
+	value, ok := secret.Data[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	hash, groups, _ := strings.Cut(string(value), ":")
+	user := &User{Username: username, PasswordHash: strings.TrimSpace(hash)}
+	if groups = strings.TrimSpace(groups); groups != "" {
+		user.Groups = strings.Split(groups, ",")
+	}
+	return user, nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MaxReplicasLimit is the largest replica count a deploy request may ask for
+const MaxReplicasLimit = 100
+
+// Grammar of image references, as used by container registries and the Docker CLI:
+//
+//	reference := name [ ":" tag ] [ "@" digest ]
+//	name      := [ domain "/" ] path-component { "/" path-component }
+//	domain    := host [ ":" port ]
+var (
+	imageDomainPattern = regexp.MustCompile(
+		`^(?:localhost|(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?$`)
+	imagePathPattern   = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	imageTagPattern    = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	imageDigestPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// maxImageNameLength is the longest repository name, domain included, registries accept
+const maxImageNameLength = 255
+
+// ImageReference is a parsed container image reference
+type ImageReference struct {
+	// Registry is the registry host, empty for the default registry
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImageReference parses a registry/repository:tag@digest image reference
+func ParseImageReference(image string) (*ImageReference, error) {
+	if image == "" {
+		return nil, fmt.Errorf("must not be empty")
+	}
+
+	ref := &ImageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !imageDigestPattern.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("invalid digest %q, expected <algorithm>:<hex>, e.g. sha256:...", ref.Digest)
+		}
+	}
+	// A colon after the last slash separates the tag; before it, it is the registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if !imageTagPattern.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("invalid tag %q, tags are up to 128 letters, digits, '_', '.' and '-'", ref.Tag)
+		}
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("missing repository name")
+	}
+	if len(name) > maxImageNameLength {
+		return nil, fmt.Errorf("repository name is longer than %d characters", maxImageNameLength)
+	}
+
+	// The first component is a registry if it looks like a host, as in the Docker CLI
+	components := strings.Split(name, "/")
+	if len(components) > 1 && (strings.ContainsAny(components[0], ".:") || components[0] == "localhost") {
+		ref.Registry, components = components[0], components[1:]
+		if !imageDomainPattern.MatchString(ref.Registry) {
+			return nil, fmt.Errorf("invalid registry %q", ref.Registry)
+		}
+	}
+	for _, component := range components {
+		if !imagePathPattern.MatchString(component) {
+			return nil, fmt.Errorf("invalid repository name %q, repository names are lowercase letters and digits separated by '.', '_', '__', '-' or '/'", strings.Join(components, "/"))
+		}
+	}
+	ref.Repository = strings.Join(components, "/")
+
+	return ref, nil
+}
+
+// validateDeployRequest checks every field of a deploy request, returning all the problems found
+func validateDeployRequest(req DeployRequest) []FieldError {
+	var fields []FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// The name is also the container name and a label value, hence a DNS label
+	if req.Name == "" {
+		invalid("name", "is required")
+	} else {
+		for _, message := range validation.IsDNS1123Label(req.Name) {
+			invalid("name", "%s", message)
+		}
+	}
+
+	if req.Image == "" {
+		invalid("image", "is required")
+	} else if _, err := ParseImageReference(req.Image); err != nil {
+		invalid("image", "%v", err)
+	}
+
+	if req.MemoryLimit == "" {
+		invalid("memoryLimit", "is required")
+	} else if quantity, err := resource.ParseQuantity(req.MemoryLimit); err != nil {
+		invalid("memoryLimit", "must be a quantity such as 128Mi or 1Gi")
+	} else if quantity.Sign() <= 0 {
+		invalid("memoryLimit", "must be greater than zero")
+	}
+
+	// Zero replicas stand for the defaults: 1 for minReplicas and minReplicas for maxReplicas
+	switch {
+	case req.MinReplicas < 0:
+		invalid("minReplicas", "must be greater than or equal to 0")
+	case req.MinReplicas > MaxReplicasLimit:
+		invalid("minReplicas", "must be less than or equal to %d", MaxReplicasLimit)
+	}
+	switch {
+	case req.MaxReplicas < 0:
+		invalid("maxReplicas", "must be greater than or equal to 0")
+	case req.MaxReplicas > MaxReplicasLimit:
+		invalid("maxReplicas", "must be less than or equal to %d", MaxReplicasLimit)
+	case req.MaxReplicas != 0 && req.MaxReplicas < req.MinReplicas:
+		invalid("maxReplicas", "must be greater than or equal to minReplicas (%d)", req.MinReplicas)
+	}
+
+	for i, configMap := range req.ConfigMaps {
+		for _, message := range validation.IsDNS1123Subdomain(configMap) {
+			invalid(fmt.Sprintf("configMaps[%d]", i), "%s", message)
+		}
+	}
+	for i, secret := range req.Secrets {
+		for _, message := range validation.IsDNS1123Subdomain(secret) {
+			invalid(fmt.Sprintf("secrets[%d]", i), "%s", message)
+		}
+	}
+
+	return fields
+}
+
+// validateImportRequest checks the names of an import request
+func validateImportRequest(req ImportRequest) []FieldError {
+	var fields []FieldError
+	if req.Deployment == "" {
+		fields = append(fields, FieldError{Field: "deployment", Message: "is required"})
+	} else {
+		for _, message := range validation.IsDNS1123Subdomain(req.Deployment) {
+			fields = append(fields, FieldError{Field: "deployment", Message: message})
+		}
+	}
+	if req.Name != "" {
+		for _, message := range validation.IsDNS1123Label(req.Name) {
+			fields = append(fields, FieldError{Field: "name", Message: message})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+	"github.com/espinozasenior/go-assesstment.git/internal/controller"
+)
+
+// Watch event types, as in Kubernetes watches
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// watchHeartbeatInterval is how often an idle stream is sent a keep-alive
+const watchHeartbeatInterval = 15 * time.Second
+
+// watchBuffer is how many events a subscriber may fall behind before it is dropped
+const watchBuffer = 32
+
+// WatchEvent is a status change of an app streamed by the watch endpoint
+type WatchEvent struct {
+	Type               string             `json:"type"`
+	Name               string             `json:"name"`
+	Namespace          string             `json:"namespace"`
+	ResourceVersion    string             `json:"resourceVersion"`
+	Status             string             `json:"status"`
+	Replicas           int32              `json:"replicas"`
+	DesiredReplicas    int32              `json:"desiredReplicas"`
+	Generation         int64              `json:"generation"`
+	ObservedGeneration int64              `json:"observedGeneration"`
+	Message            string             `json:"message,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+func newWatchEvent(eventType string, app *deskreev1.AppDeployment) WatchEvent {
+	event := WatchEvent{
+		Type:               eventType,
+		Name:               app.Name,
+		Namespace:          app.Namespace,
+		ResourceVersion:    app.ResourceVersion,
+		Status:             app.Status.State,
+		Replicas:           app.Status.AvailableReplicas,
+		DesiredReplicas:    app.Spec.MinReplicas,
+		Generation:         app.Generation,
+		ObservedGeneration: app.Status.ObservedGeneration,
+		Message:            app.Status.Message,
+		Conditions:         app.Status.Conditions,
+	}
+	if !app.DeletionTimestamp.IsZero() && event.Status != controller.StateTerminating {
+		event.Status = controller.StateTerminating
+	}
+	return event
+}
+
+// watchSubscriber receives the events of one app
+type watchSubscriber struct {
+	namespace, name string
+	events          chan WatchEvent
+}
+
+// watchHub fans the events of the AppDeployment informer out to the open watches, so every
+// watch shares one informer event handler
+type watchHub struct {
+	mu          sync.Mutex
+	subscribers map[*watchSubscriber]struct{}
+}
+
+func (h *watchHub) subscribe(namespace, name string) *watchSubscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriber := &watchSubscriber{namespace: namespace, name: name, events: make(chan WatchEvent, watchBuffer)}
+	h.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (h *watchHub) unsubscribe(subscriber *watchSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[subscriber]; ok {
+		delete(h.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// publish hands an event to the subscribers of its app. A subscriber too far behind is
+// dropped, ending its stream; the client resumes from the last resourceVersion it saw.
+func (h *watchHub) publish(eventType string, obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	app, ok := obj.(*deskreev1.AppDeployment)
+	if !ok {
+		return
+	}
+	event := newWatchEvent(eventType, app)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscriber := range h.subscribers {
+		if subscriber.namespace != app.Namespace || subscriber.name != app.Name {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			apiLog.Info("Dropping slow watch", "namespace", app.Namespace, "name", app.Name)
+			delete(h.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+}
+
+// startWatchHub registers the watch hub with the AppDeployment informer; watches are
+// unavailable when the server has no Informers
+func (s *Server) startWatchHub() {
+	s.watchOnce.Do(func() {
+		if s.Informers == nil {
+			return
+		}
+
+		informer, err := s.Informers.GetInformer(context.Background(), &deskreev1.AppDeployment{})
+		if err != nil {
+			apiLog.Error(err, "Failed to get AppDeployment informer, watches are unavailable")
+			return
+		}
+
+		hub := &watchHub{subscribers: map[*watchSubscriber]struct{}{}}
+		if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { hub.publish(WatchEventAdded, obj) },
+			UpdateFunc: func(_, obj interface{}) { hub.publish(WatchEventModified, obj) },
+			DeleteFunc: func(obj interface{}) { hub.publish(WatchEventDeleted, obj) },
+		}); err != nil {
+			apiLog.Error(err, "Failed to watch AppDeployments, watches are unavailable")
+			return
+		}
+		s.watches = hub
+	})
+}
+
+// HandleWatchApp streams the status changes of an app, as Server-Sent Events or, when the
+// request asks for an upgrade, as JSON WebSocket messages. The stream starts with the current
+// state unless the client already saw it, as told by the resourceVersion query parameter or
+// the Last-Event-ID header of a reconnecting EventSource, and ends when the app is deleted.
+func (s *Server) HandleWatchApp(w http.ResponseWriter, r *http.Request) {
+	if s.watches == nil {
+		writeError(w, http.StatusServiceUnavailable, "Watching apps is not available on this server")
+		return
+	}
+
+	namespace, name := RequestNamespace(r), r.PathValue("name")
+	since := r.URL.Query().Get("resourceVersion")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		since = lastEventID
+	}
+
+	// Subscribe before reading the current state so no change falls in between
+	subscriber := s.watches.subscribe(namespace, name)
+	defer s.watches.unsubscribe(subscriber)
+
+	// Read the current state as the caller, so a caller cannot watch an app it cannot get
+	var initial *WatchEvent
+	app := &deskreev1.AppDeployment{}
+	err := s.readerFor(r).Get(r.Context(), types.NamespacedName{Name: name, Namespace: namespace}, app)
+	switch {
+	case err != nil:
+		writeAPIError(w, err, "Failed to get AppDeployment")
+		return
+	case app.ResourceVersion != since:
+		event := newWatchEvent(WatchEventAdded, app)
+		initial = &event
+	}
+
+	ctx, cancel := s.streamContext(r)
+	defer cancel()
+
+	stream := &watchStream{subscriber: subscriber, initial: initial, lastVersion: app.ResourceVersion}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		websocket.Server{
+			Handshake: func(*websocket.Config, *http.Request) error { return checkOrigin(r) },
+			Handler:   func(conn *websocket.Conn) { stream.serveWebSocket(ctx, conn) },
+		}.ServeHTTP(w, r)
+		return
+	}
+	stream.serveSSE(w, r.WithContext(ctx))
+}
+
+// checkOrigin refuses a WebSocket upgrade from a browser page served by another host.
+// Clients that are not browsers send no Origin and are let through.
+func checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid Origin %q: %w", origin, err)
+	}
+	if !strings.EqualFold(u.Host, r.Host) {
+		return fmt.Errorf("origin %q does not match host %q", origin, r.Host)
+	}
+	return nil
+}
+
+// watchStream writes the events of one subscriber to a client
+type watchStream struct {
+	subscriber  *watchSubscriber
+	initial     *WatchEvent
+	lastVersion string
+}
+
+// next returns the next event to send, skipping the changes the client has already seen
+func (ws *watchStream) next(done <-chan struct{}, heartbeat <-chan time.Time) (event WatchEvent, ok, beat bool) {
+	if ws.initial != nil {
+		event, ws.initial = *ws.initial, nil
+		return event, true, false
+	}
+
+	for {
+		select {
+		case event, open := <-ws.subscriber.events:
+			if !open {
+				return WatchEvent{}, false, false
+			}
+			if event.Type != WatchEventDeleted && event.ResourceVersion == ws.lastVersion {
+				continue
+			}
+			ws.lastVersion = event.ResourceVersion
+			return event, true, false
+		case <-heartbeat:
+			return WatchEvent{}, true, true
+		case <-done:
+			return WatchEvent{}, false, false
+		}
+	}
+}
+
+func (ws *watchStream) serveSSE(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		apiLog.Error(err, "Streaming is not supported by the response writer")
+		return
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		event, ok, beat := ws.next(r.Context().Done(), heartbeat.C)
+		if !ok {
+			return
+		}
+
+		if beat {
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			apiLog.Error(err, "Failed to encode watch event")
+			return
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ResourceVersion, event.Type, data); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if event.Type == WatchEventDeleted {
+			return
+		}
+	}
+}
+
+func (ws *watchStream) serveWebSocket(ctx context.Context, conn *websocket.Conn) {
+	// The client sends nothing; a read returning means it went away
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var discard []byte
+		for websocket.Message.Receive(conn, &discard) == nil {
+		}
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		// WebSocket connections need no keep-alive comments, so there is no heartbeat
+		event, ok, _ := ws.next(done, nil)
+		if !ok {
+			return
+		}
+
+		if err := websocket.JSON.Send(conn, event); err != nil {
+			return
+		}
+		if event.Type == WatchEventDeleted {
+			return
+		}
+	}
+}
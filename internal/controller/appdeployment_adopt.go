@@ -0,0 +1,155 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// ConditionAdopted reports that the AppDeployment took over a pre-existing Deployment
+const ConditionAdopted = "Adopted"
+
+// adoptDeployment handles a Deployment that exists under the AppDeployment's name but is not
+// controlled by it. With spec.adopt set and no other controller on the Deployment, the
+// AppDeployment becomes its controller and unset spec fields are back-filled from it.
+// It returns whether the Deployment was adopted; otherwise the status explains why not.
+func (r *AppDeploymentReconciler) adoptDeployment(ctx context.Context, app *deskreev1.AppDeployment, deployment *appsv1.Deployment) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	if owner := metav1.GetControllerOf(deployment); owner != nil {
+		app.Status.State = StateFailed
+		app.Status.Message = fmt.Sprintf("Deployment %s is already controlled by %s %s", deployment.Name, owner.Kind, owner.Name)
+		return false, nil
+	}
+
+	if !app.Spec.Adopt {
+		app.Status.State = StateFailed
+		app.Status.Message = fmt.Sprintf("Deployment %s already exists and is not managed by this AppDeployment; set spec.adopt to take it over", deployment.Name)
+		return false, nil
+	}
+
+	if err := controllerutil.SetControllerReference(app, deployment, r.Scheme); err != nil {
+		return false, err
+	}
+	if err := r.Update(ctx, deployment); err != nil {
+		return false, err
+	}
+
+	backfillSpecFromDeployment(&app.Spec, deployment)
+	if err := r.Update(ctx, app); err != nil {
+		return false, err
+	}
+
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:    ConditionAdopted,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DeploymentAdopted",
+		Message: fmt.Sprintf("Took over existing Deployment %s", deployment.Name),
+	})
+	logger.Info("Adopted existing Deployment", "DeploymentName", deployment.Name)
+	return true, nil
+}
+
+// backfillSpecFromDeployment copies the shape of an existing Deployment into the fields of
+// the AppDeployment spec that have not been set
+func backfillSpecFromDeployment(spec *deskreev1.AppDeploymentSpec, deployment *appsv1.Deployment) {
+	if spec.Selector == nil {
+		spec.Selector = deployment.Spec.Selector.DeepCopy()
+	}
+
+	if spec.MinReplicas == 0 && deployment.Spec.Replicas != nil {
+		spec.MinReplicas = *deployment.Spec.Replicas
+	}
+	if spec.MaxReplicas < spec.MinReplicas {
+		spec.MaxReplicas = spec.MinReplicas
+	}
+
+	if len(spec.Template.ObjectMeta.Labels) == 0 {
+		spec.Template.ObjectMeta.Labels = deployment.Spec.Template.Labels
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return
+	}
+
+	// spec.memoryLimit applies to every container, so it is only taken over when they all agree
+	if spec.MemoryLimit == "" {
+		spec.MemoryLimit = commonMemoryLimit(containers)
+	}
+
+	if len(spec.Template.Spec.Containers) == 0 {
+		for _, c := range containers {
+			container := deskreev1.Container{
+				Name:  c.Name,
+				Image: c.Image,
+			}
+			for _, port := range c.Ports {
+				container.Ports = append(container.Ports, deskreev1.ContainerPort{ContainerPort: port.ContainerPort})
+			}
+			for _, source := range c.EnvFrom {
+				switch {
+				case source.ConfigMapRef != nil:
+					container.EnvFrom = append(container.EnvFrom, deskreev1.EnvFromSource{
+						ConfigMapRef: &deskreev1.LocalObjectReference{Name: source.ConfigMapRef.Name},
+					})
+				case source.SecretRef != nil:
+					container.EnvFrom = append(container.EnvFrom, deskreev1.EnvFromSource{
+						SecretRef: &deskreev1.LocalObjectReference{Name: source.SecretRef.Name},
+					})
+				}
+			}
+			spec.Template.Spec.Containers = append(spec.Template.Spec.Containers, container)
+		}
+	}
+
+	if spec.Image == "" {
+		spec.Image = spec.Template.Spec.Containers[0].Image
+	}
+}
+
+// commonMemoryLimit returns the memory limit shared by all the containers, or "" when any of
+// them has none or they differ
+func commonMemoryLimit(containers []corev1.Container) string {
+	var common *resource.Quantity
+	for _, c := range containers {
+		limit, ok := c.Resources.Limits[corev1.ResourceMemory]
+		if !ok {
+			return ""
+		}
+		if common == nil {
+			common = &limit
+		} else if limit.Cmp(*common) != 0 {
+			return ""
+		}
+	}
+	if common == nil {
+		return ""
+	}
+	return common.String()
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// applyDeployment server-side applies the desired Deployment under FieldManager. Unless force
+// is set, fields owned by another manager surface as a Conflict error instead of being
+// overwritten. On success desired holds the live Deployment.
+func (r *AppDeploymentReconciler) applyDeployment(ctx context.Context, desired *appsv1.Deployment, force bool) error {
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return r.Patch(ctx, desired, client.Apply, opts...)
+}
+
+// hasApplied reports whether FieldManager has applied the Deployment before. An adopted
+// Deployment has not, and its fields still belong to whoever created it.
+func hasApplied(deployment *appsv1.Deployment) bool {
+	for _, entry := range deployment.ManagedFields {
+		if entry.Manager == FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// DeploymentName returns the name of the Deployment an AppDeployment manages: the adopted
+// Deployment named by spec.appName, or one named after the AppDeployment
+func DeploymentName(app *deskreev1.AppDeployment) string {
+	if app.Spec.AppName != "" {
+		return app.Spec.AppName
+	}
+	return app.Name
+}
+
+// desiredDeployment builds the apply configuration of the Deployment an AppDeployment produces.
+// Only fields the controller wants to own are set. live is the existing Deployment, or nil
+// when there is none yet.
+func desiredDeployment(app *deskreev1.AppDeployment, live *appsv1.Deployment, name, configHash string) (*appsv1.Deployment, error) {
+	replicas := desiredReplicas(app, live)
+
+	var limits corev1.ResourceList
+	if app.Spec.MemoryLimit != "" {
+		memoryLimit, err := resource.ParseQuantity(app.Spec.MemoryLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memoryLimit %q: %w", app.Spec.MemoryLimit, err)
+		}
+		limits = corev1.ResourceList{corev1.ResourceMemory: memoryLimit}
+	}
+
+	// Annotate the pod template with the configuration hash
+	var templateAnnotations map[string]string
+	if configHash != "" {
+		templateAnnotations = map[string]string{ConfigHashAnnotation: configHash}
+	}
+
+	// Pods carry the template labels plus whatever the selector matches on
+	templateLabels := map[string]string{}
+	for k, v := range app.Spec.Template.ObjectMeta.Labels {
+		templateLabels[k] = v
+	}
+	if app.Spec.Selector != nil {
+		for k, v := range app.Spec.Selector.MatchLabels {
+			templateLabels[k] = v
+		}
+	}
+
+	containers := make([]corev1.Container, 0, len(app.Spec.Template.Spec.Containers))
+	for _, c := range app.Spec.Template.Spec.Containers {
+		container := corev1.Container{
+			Name:      c.Name,
+			Image:     c.Image,
+			Resources: corev1.ResourceRequirements{Limits: limits},
+		}
+		for _, port := range c.Ports {
+			container.Ports = append(container.Ports, corev1.ContainerPort{
+				ContainerPort: port.ContainerPort,
+				Protocol:      corev1.ProtocolTCP,
+			})
+		}
+		// Map the referenced ConfigMaps and Secrets into the container environment
+		for _, source := range c.EnvFrom {
+			switch {
+			case source.ConfigMapRef != nil:
+				container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+					ConfigMapRef: &corev1.ConfigMapEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: source.ConfigMapRef.Name},
+					},
+				})
+			case source.SecretRef != nil:
+				container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: source.SecretRef.Name},
+					},
+				})
+			}
+		}
+		containers = append(containers, container)
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(app, deskreev1.GroupVersion.WithKind("AppDeployment")),
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: app.Spec.Selector,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      templateLabels,
+					Annotations: templateAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers: containers,
+				},
+			},
+		},
+	}, nil
+}
+
+// desiredReplicas returns the replica count to apply. A new Deployment starts at
+// spec.minReplicas; an existing one keeps its current count as long as it lies between
+// spec.minReplicas and spec.maxReplicas, so that scaling by an autoscaler is not reset by
+// the next apply.
+func desiredReplicas(app *deskreev1.AppDeployment, live *appsv1.Deployment) int32 {
+	minReplicas := app.Spec.MinReplicas
+	if minReplicas == 0 {
+		minReplicas = 1 // Default to 1 replica if not specified
+	}
+	maxReplicas := app.Spec.MaxReplicas
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+
+	if live == nil || live.Spec.Replicas == nil {
+		return minReplicas
+	}
+	return min(max(*live.Spec.Replicas, minReplicas), maxReplicas)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+const (
+	// ConfigHashAnnotation is set on the pod template with the combined hash of the referenced
+	// ConfigMaps and Secrets, so that a change in their content rolls the pods
+	ConfigHashAnnotation = "deskree.platform.deskree.com/config-hash"
+
+	// configMapRefIndex indexes AppDeployments by the ConfigMaps their containers consume
+	configMapRefIndex = ".spec.template.spec.containers.envFrom.configMapRef.name"
+	// secretRefIndex indexes AppDeployments by the Secrets their containers consume
+	secretRefIndex = ".spec.template.spec.containers.envFrom.secretRef.name"
+
+	// missingConfigHash stands in for a referenced object that does not exist yet, so that
+	// creating it later also triggers a rollout
+	missingConfigHash = "missing"
+)
+
+// referencedConfigMaps returns the names of the ConfigMaps consumed by the AppDeployment containers
+func referencedConfigMaps(app *deskreev1.AppDeployment) []string {
+	var names []string
+	for _, container := range app.Spec.Template.Spec.Containers {
+		for _, source := range container.EnvFrom {
+			if source.ConfigMapRef != nil && source.ConfigMapRef.Name != "" {
+				names = append(names, source.ConfigMapRef.Name)
+			}
+		}
+	}
+	return names
+}
+
+// referencedSecrets returns the names of the Secrets consumed by the AppDeployment containers
+func referencedSecrets(app *deskreev1.AppDeployment) []string {
+	var names []string
+	for _, container := range app.Spec.Template.Spec.Containers {
+		for _, source := range container.EnvFrom {
+			if source.SecretRef != nil && source.SecretRef.Name != "" {
+				names = append(names, source.SecretRef.Name)
+			}
+		}
+	}
+	return names
+}
+
+// configHashes computes a content hash for every ConfigMap and Secret referenced by the AppDeployment,
+// keyed by "configmap/<name>" or "secret/<name>"
+func (r *AppDeploymentReconciler) configHashes(ctx context.Context, app *deskreev1.AppDeployment) (map[string]string, error) {
+	hashes := map[string]string{}
+
+	for _, name := range referencedConfigMaps(app) {
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: app.Namespace}, configMap)
+		switch {
+		case errors.IsNotFound(err):
+			hashes["configmap/"+name] = missingConfigHash
+		case err != nil:
+			return nil, fmt.Errorf("error getting configmap %s: %w", name, err)
+		default:
+			binary := make(map[string]string, len(configMap.BinaryData))
+			for k, v := range configMap.BinaryData {
+				binary[k] = string(v)
+			}
+			hashes["configmap/"+name] = hashData(configMap.Data, binary)
+		}
+	}
+
+	for _, name := range referencedSecrets(app) {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: app.Namespace}, secret)
+		switch {
+		case errors.IsNotFound(err):
+			hashes["secret/"+name] = missingConfigHash
+		case err != nil:
+			return nil, fmt.Errorf("error getting secret %s: %w", name, err)
+		default:
+			data := make(map[string]string, len(secret.Data))
+			for k, v := range secret.Data {
+				data[k] = string(v)
+			}
+			hashes["secret/"+name] = hashData(data, secret.StringData)
+		}
+	}
+
+	return hashes, nil
+}
+
+// hashData returns a stable hash of the given key/value sets
+func hashData(sets ...map[string]string) string {
+	h := sha256.New()
+	for i, data := range sets {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%d/%s=%q;", i, k, data[k])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// combinedConfigHash folds the per-source hashes into the single value stored on the pod template.
+// It returns an empty string when the AppDeployment does not reference any configuration.
+func combinedConfigHash(hashes map[string]string) string {
+	if len(hashes) == 0 {
+		return ""
+	}
+	return hashData(hashes)
+}
+
+// describeConfigChange explains which referenced sources differ between two sets of hashes
+func describeConfigChange(previous, current map[string]string) string {
+	var changed []string
+	for source, hash := range current {
+		if previous[source] != hash {
+			changed = append(changed, source)
+		}
+	}
+	for source := range previous {
+		if _, ok := current[source]; !ok {
+			changed = append(changed, source)
+		}
+	}
+	if len(changed) == 0 {
+		return "Referenced configuration changed"
+	}
+	sort.Strings(changed)
+	return fmt.Sprintf("%s changed", strings.Join(changed, ", "))
+}
+
+// indexConfigMapRefs is the field indexer function for configMapRefIndex
+func indexConfigMapRefs(obj client.Object) []string {
+	return referencedConfigMaps(obj.(*deskreev1.AppDeployment))
+}
+
+// indexSecretRefs is the field indexer function for secretRefIndex
+func indexSecretRefs(obj client.Object) []string {
+	return referencedSecrets(obj.(*deskreev1.AppDeployment))
+}
+
+// appDeploymentsReferencing returns a map function that enqueues every AppDeployment in the
+// object's namespace whose field index entry matches the object name
+func (r *AppDeploymentReconciler) appDeploymentsReferencing(index string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		appDeployments := &deskreev1.AppDeploymentList{}
+		if err := r.List(ctx, appDeployments,
+			client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{index: obj.GetName()},
+		); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list AppDeployments referencing object",
+				"Index", index, "Name", obj.GetName())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(appDeployments.Items))
+		for _, item := range appDeployments.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace},
+			})
+		}
+		return requests
+	}
+}
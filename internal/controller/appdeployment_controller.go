@@ -19,16 +19,22 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
@@ -41,17 +47,38 @@ const (
 	StateRunning = "Running"
 	// StateFailed indicates the deployment has failed
 	StateFailed = "Failed"
+	// StateTerminating indicates the AppDeployment is being torn down
+	StateTerminating = "Terminating"
+
+	// appDeploymentFinalizer guards the teardown sequence run in reconcileDelete
+	appDeploymentFinalizer = "deskree.platform.deskree.com/finalizer"
+
+	// FieldManager is the server-side apply field manager the controller owns its fields under
+	FieldManager = "appdeployment-controller"
+
+	// ConditionFieldConflict reports that another field manager owns fields the controller wants to set
+	ConditionFieldConflict = "FieldConflict"
+
+	// conflictRequeueInterval is how long to wait before retrying an apply that hit a field conflict
+	conflictRequeueInterval = time.Minute
 )
 
 // AppDeploymentReconciler reconciles a AppDeployment object
 type AppDeploymentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Recorder publishes the Kubernetes Events shown by kubectl describe appdeployment
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=deskree.platform.deskree.com,resources=appdeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deskree.platform.deskree.com,resources=appdeployments/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=deskree.platform.deskree.com,resources=appdeployments/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,64 +105,195 @@ func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
+	// Tear down owned resources before letting the AppDeployment go
+	if !appDeployment.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, appDeployment)
+	}
+
+	if !controllerutil.ContainsFinalizer(appDeployment, appDeploymentFinalizer) {
+		controllerutil.AddFinalizer(appDeployment, appDeploymentFinalizer)
+		if err := r.Update(ctx, appDeployment); err != nil {
+			logger.Error(err, "Failed to add finalizer to AppDeployment")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Hash the referenced ConfigMaps and Secrets so content changes roll the pods
+	configHashes, err := r.configHashes(ctx, appDeployment)
+	if err != nil {
+		logger.Error(err, "Failed to hash referenced configuration")
+		return ctrl.Result{}, err
+	}
+
 	// Check if the deployment exists
 	deployment := &appsv1.Deployment{}
-	deploymentName := appDeployment.Spec.AppName
-	if deploymentName == "" {
-		deploymentName = appDeployment.Name
-	}
+	deploymentName := DeploymentName(appDeployment)
 
 	err = r.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: req.Namespace}, deployment)
+	result := ctrl.Result{}
+	previousState := appDeployment.Status.State
+	exists := err == nil
 
 	// Update the AppDeployment status based on the deployment status
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Deployment doesn't exist yet, create it
-			logger.Info("Creating a new Deployment", "DeploymentName", deploymentName)
+	if err != nil && !errors.IsNotFound(err) {
+		// Error getting deployment
+		appDeployment.Status.State = StateFailed
+		appDeployment.Status.Message = fmt.Sprintf("Error getting deployment: %v", err)
+		appDeployment.Status.AvailableReplicas = 0
+		logger.Error(err, "Failed to get Deployment for AppDeployment", "DeploymentName", deploymentName)
+	} else if exists && !metav1.IsControlledBy(deployment, appDeployment) {
+		// A Deployment with this name exists but is not ours, take it over if asked to
+		adopted, err := r.adoptDeployment(ctx, appDeployment, deployment)
+		if err != nil {
+			logger.Error(err, "Failed to adopt Deployment", "DeploymentName", deploymentName)
+			return ctrl.Result{}, err
+		}
+		if adopted {
+			// Reconcile again against the back-filled spec
+			appDeployment.Status.State = StatePending
+			appDeployment.Status.Message = "Adopted existing deployment"
+			r.Recorder.Eventf(appDeployment, corev1.EventTypeNormal, "Adopted", "Adopted existing Deployment %s", deploymentName)
+			result.Requeue = true
+		} else {
+			appDeployment.Status.AvailableReplicas = 0
+			logger.Info("Deployment exists and is not managed by this AppDeployment", "DeploymentName", deploymentName)
+		}
+	} else if len(appDeployment.Spec.Template.Spec.Containers) == 0 {
+		// Nothing to adopt and nothing to build a Deployment from
+		appDeployment.Status.State = StateFailed
+		appDeployment.Status.Message = fmt.Sprintf("Deployment %s not found and spec.template defines no containers", deploymentName)
+		appDeployment.Status.AvailableReplicas = 0
+		logger.Info("Cannot create Deployment without containers", "DeploymentName", deploymentName)
+	} else {
+		// A changed configuration hash on the pod template rolls the pods
+		configHash := combinedConfigHash(configHashes)
+		rolloutTrigger := ""
+		if exists && deployment.Spec.Template.Annotations[ConfigHashAnnotation] != configHash {
+			rolloutTrigger = describeConfigChange(appDeployment.Status.ConfigHashes, configHashes)
+		}
 
-			// Create a new deployment based on the AppDeployment spec
-			_, err = r.createDeploymentFromAppDeployment(appDeployment, req.Namespace)
-			if err != nil {
+		var live *appsv1.Deployment
+		if exists {
+			live = deployment
+		}
+		desired, err := desiredDeployment(appDeployment, live, deploymentName, configHash)
+		if err != nil {
+			appDeployment.Status.State = StateFailed
+			appDeployment.Status.Message = fmt.Sprintf("Invalid AppDeployment spec: %v", err)
+			appDeployment.Status.AvailableReplicas = 0
+			logger.Error(err, "Failed to build Deployment for AppDeployment", "DeploymentName", deploymentName)
+			r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "InvalidSpec", appDeployment.Status.Message)
+			return ctrl.Result{}, r.Status().Update(ctx, appDeployment)
+		}
+
+		// With the spec and configuration unchanged since the last apply, any difference
+		// between the desired and the live Deployment was made outside the controller
+		var drift []deskreev1.FieldDiff
+		if exists && rolloutTrigger == "" && appDeployment.Status.ObservedGeneration == appDeployment.Generation {
+			drift = diffDeployment(desired, deployment)
+		}
+		revert := appDeployment.Spec.DriftPolicy == deskreev1.DriftPolicyRevert
+
+		if len(drift) > 0 && !revert {
+			// Leave the live Deployment alone and report what changed
+			appDeployment.Status.Drift = drift
+			if meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+				Type:    ConditionDrifted,
+				Status:  metav1.ConditionTrue,
+				Reason:  "DriftDetected",
+				Message: fmt.Sprintf("Changed outside the controller: %s", describeDrift(drift)),
+			}) {
+				r.Recorder.Eventf(appDeployment, corev1.EventTypeWarning, "DriftDetected", "Deployment %s changed outside the controller: %s",
+					deploymentName, describeDrift(drift))
+			}
+			logger.Info("Drift detected", "DeploymentName", deploymentName, "Fields", describeDrift(drift))
+		} else {
+			// The revert policy lets the controller win over other field managers. The first
+			// apply to an adopted Deployment takes its fields over from the previous manager,
+			// or every later spec change would conflict with it.
+			force := revert || (exists && !hasApplied(deployment))
+			err = r.applyDeployment(ctx, desired, force)
+			switch {
+			case errors.IsConflict(err):
+				// Someone else owns fields we want to set, report it and back off instead of fighting
+				if meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+					Type:    ConditionFieldConflict,
+					Status:  metav1.ConditionTrue,
+					Reason:  "ApplyConflict",
+					Message: err.Error(),
+				}) {
+					r.Recorder.Eventf(appDeployment, corev1.EventTypeWarning, "FieldConflict", "Cannot apply Deployment %s: %v", deploymentName, err)
+				}
+				logger.Info("Field conflict applying Deployment", "DeploymentName", deploymentName, "Conflict", err.Error())
+				result.RequeueAfter = conflictRequeueInterval
+			case err != nil:
 				appDeployment.Status.State = StateFailed
-				appDeployment.Status.Message = fmt.Sprintf("Failed to create deployment: %v", err)
+				appDeployment.Status.Message = fmt.Sprintf("Failed to apply deployment: %v", err)
 				appDeployment.Status.AvailableReplicas = 0
-				logger.Error(err, "Failed to create Deployment for AppDeployment", "DeploymentName", deploymentName)
+				logger.Error(err, "Failed to apply Deployment for AppDeployment", "DeploymentName", deploymentName)
+				r.Recorder.Event(appDeployment, corev1.EventTypeWarning, "ApplyFailed", appDeployment.Status.Message)
 				return ctrl.Result{}, err
+			default:
+				meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+					Type:    ConditionFieldConflict,
+					Status:  metav1.ConditionFalse,
+					Reason:  "Applied",
+					Message: "All managed fields applied",
+				})
+				if len(drift) > 0 {
+					meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+						Type:    ConditionDrifted,
+						Status:  metav1.ConditionFalse,
+						Reason:  "DriftReverted",
+						Message: fmt.Sprintf("Reverted changes made outside the controller: %s", describeDrift(drift)),
+					})
+					logger.Info("Drift reverted", "DeploymentName", deploymentName, "Fields", describeDrift(drift))
+					r.Recorder.Eventf(appDeployment, corev1.EventTypeNormal, "DriftReverted", "Reverted changes to Deployment %s made outside the controller: %s",
+						deploymentName, describeDrift(drift))
+				} else {
+					meta.SetStatusCondition(&appDeployment.Status.Conditions, metav1.Condition{
+						Type:    ConditionDrifted,
+						Status:  metav1.ConditionFalse,
+						Reason:  "InSync",
+						Message: "Generated resources match the AppDeployment",
+					})
+				}
+				appDeployment.Status.Drift = nil
+				if exists {
+					r.recordUpdate(appDeployment, deployment, desired)
+				}
+				appDeployment.Status.ObservedGeneration = appDeployment.Generation
+				deployment = desired
+				if rolloutTrigger != "" {
+					now := metav1.Now()
+					appDeployment.Status.LastRolloutTrigger = rolloutTrigger
+					appDeployment.Status.LastRolloutTime = &now
+					logger.Info("Rolling restart triggered", "DeploymentName", deploymentName, "Trigger", rolloutTrigger)
+					r.Recorder.Eventf(appDeployment, corev1.EventTypeNormal, "RolloutRestart", "Restarting the pods of Deployment %s: %s",
+						deploymentName, rolloutTrigger)
+				}
+				appDeployment.Status.ConfigHashes = configHashes
 			}
+		}
 
-			// Set the deployment status
+		if !exists && err == nil {
 			appDeployment.Status.State = StatePending
 			appDeployment.Status.Message = "Deployment created, waiting for replicas"
 			appDeployment.Status.AvailableReplicas = 0
 			logger.Info("Deployment created", "DeploymentName", deploymentName)
-		} else {
-			// Error getting deployment
-			appDeployment.Status.State = StateFailed
-			appDeployment.Status.Message = fmt.Sprintf("Error getting deployment: %v", err)
-			appDeployment.Status.AvailableReplicas = 0
-			logger.Error(err, "Failed to get Deployment for AppDeployment", "DeploymentName", deploymentName)
+			r.Recorder.Eventf(appDeployment, corev1.EventTypeNormal, "Created", "Created Deployment %s", deploymentName)
+		} else if exists {
+			r.setStatusFromDeployment(ctx, appDeployment, deployment)
 		}
-	} else {
-		availableReplicas := deployment.Status.AvailableReplicas
-		desiredReplicas := *deployment.Spec.Replicas
 
-		appDeployment.Status.AvailableReplicas = availableReplicas
-
-		if availableReplicas == 0 {
-			appDeployment.Status.State = StatePending
-			appDeployment.Status.Message = "Deployment has no available replicas"
-			logger.Info("Deployment has no available replicas", "DeploymentName", deploymentName)
-		} else if availableReplicas < desiredReplicas {
-			appDeployment.Status.State = StatePending
-			appDeployment.Status.Message = fmt.Sprintf("Deployment is scaling up: %d/%d replicas available", availableReplicas, desiredReplicas)
-			logger.Info("Deployment is scaling up", "DeploymentName", deploymentName, "AvailableReplicas", availableReplicas, "DesiredReplicas", desiredReplicas)
-		} else {
-			appDeployment.Status.State = StateRunning
-			appDeployment.Status.Message = fmt.Sprintf("Deployment is active with %d replica(s)", availableReplicas)
-			logger.Info("Deployment is running", "DeploymentName", deploymentName, "AvailableReplicas", availableReplicas)
+		// Keep checking on a rollout in progress, pod failures do not trigger a reconcile
+		if appDeployment.Status.State == StatePending && !result.Requeue && result.RequeueAfter == 0 {
+			result.RequeueAfter = rolloutCheckInterval
 		}
 	}
 
+	r.recordStateChange(appDeployment, previousState)
+
 	// Update the AppDeployment status
 	err = r.Status().Update(ctx, appDeployment)
 	if err != nil {
@@ -143,72 +301,111 @@ func (r *AppDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AppDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &deskreev1.AppDeployment{}, configMapRefIndex, indexConfigMapRefs); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &deskreev1.AppDeployment{}, secretRefIndex, indexSecretRefs); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&deskreev1.AppDeployment{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&batchv1.Job{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.appDeploymentsReferencing(configMapRefIndex))).
+		// Only the metadata of Secrets is cached; configHashes reads their content when it needs it
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.appDeploymentsReferencing(secretRefIndex)),
+			builder.OnlyMetadata).
 		Named("appdeployment").
 		Complete(r)
 }
 
-// createDeploymentFromAppDeployment creates a new Deployment from an AppDeployment resource
-func (r *AppDeploymentReconciler) createDeploymentFromAppDeployment(app *deskreev1.AppDeployment, namespace string) (*appsv1.Deployment, error) {
-	// Set the deployment name
-	deploymentName := app.Spec.AppName
-	if deploymentName == "" {
-		deploymentName = app.Name
-	}
-
-	// Set the replicas
-	replicas := app.Spec.MinReplicas
-	if replicas == 0 {
-		replicas = 1 // Default to 1 replica if not specified
-	}
-
-	// Create the deployment
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
-			Namespace: namespace,
-			Labels:    app.Labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(app, deskreev1.GroupVersion.WithKind("AppDeployment")),
-			},
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
-			Selector: app.Spec.Selector,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: app.Spec.Selector.MatchLabels,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  app.Spec.Template.Spec.Containers[0].Name,
-							Image: app.Spec.Template.Spec.Containers[0].Image,
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: app.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort,
-								},
-							},
-							Resources: corev1.ResourceRequirements{
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse(app.Spec.MemoryLimit),
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Create the deployment in the cluster
-	err := r.Create(context.Background(), deployment)
-	return deployment, err
+// setStatusFromDeployment derives the AppDeployment state from the replicas of its Deployment,
+// explaining a stalled rollout with the reasons its pods fail
+func (r *AppDeploymentReconciler) setStatusFromDeployment(ctx context.Context, app *deskreev1.AppDeployment, deployment *appsv1.Deployment) {
+	logger := log.FromContext(ctx)
+
+	availableReplicas := deployment.Status.AvailableReplicas
+	desiredReplicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desiredReplicas = *deployment.Spec.Replicas
+	}
+
+	app.Status.AvailableReplicas = availableReplicas
+
+	if message, ok := progressDeadlineExceeded(deployment); ok {
+		app.Status.State = StateFailed
+		app.Status.Message = fmt.Sprintf("Rollout failed: %s", message)
+		logger.Info("Deployment exceeded its progress deadline", "DeploymentName", deployment.Name)
+	} else if availableReplicas == 0 {
+		app.Status.State = StatePending
+		app.Status.Message = "Deployment has no available replicas"
+		logger.Info("Deployment has no available replicas", "DeploymentName", deployment.Name)
+	} else if availableReplicas < desiredReplicas {
+		app.Status.State = StatePending
+		app.Status.Message = fmt.Sprintf("Deployment is scaling up: %d/%d replicas available", availableReplicas, desiredReplicas)
+		logger.Info("Deployment is scaling up", "DeploymentName", deployment.Name, "AvailableReplicas", availableReplicas, "DesiredReplicas", desiredReplicas)
+	} else {
+		app.Status.State = StateRunning
+		app.Status.Message = fmt.Sprintf("Deployment is active with %d replica(s)", availableReplicas)
+		logger.Info("Deployment is running", "DeploymentName", deployment.Name, "AvailableReplicas", availableReplicas)
+	}
+
+	failures, err := r.podFailures(ctx, deployment)
+	if err != nil {
+		logger.Error(err, "Failed to check the pods of Deployment", "DeploymentName", deployment.Name)
+		return
+	}
+	if len(failures) == 0 {
+		meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+			Type:    ConditionPodsFailing,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoFailures",
+			Message: "No pod is failing",
+		})
+		return
+	}
+
+	if meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:    ConditionPodsFailing,
+		Status:  metav1.ConditionTrue,
+		Reason:  failures[0].Reason,
+		Message: describePodFailures(failures),
+	}) {
+		r.Recorder.Event(app, corev1.EventTypeWarning, "PodsFailing", describePodFailures(failures))
+	}
+	if app.Status.State != StateRunning {
+		app.Status.Message = fmt.Sprintf("%s; %s", app.Status.Message, describePodFailures(failures))
+	}
+	logger.Info("Pods are failing", "DeploymentName", deployment.Name, "Failures", describePodFailures(failures))
+}
+
+// recordUpdate records the changes a new generation of the AppDeployment applied to its Deployment
+func (r *AppDeploymentReconciler) recordUpdate(app *deskreev1.AppDeployment, live, desired *appsv1.Deployment) {
+	if live.Spec.Replicas != nil && desired.Spec.Replicas != nil && *live.Spec.Replicas != *desired.Spec.Replicas {
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, "Scaled", "Scaled Deployment %s from %d to %d replicas",
+			desired.Name, *live.Spec.Replicas, *desired.Spec.Replicas)
+	}
+	if app.Status.ObservedGeneration != 0 && app.Status.ObservedGeneration != app.Generation {
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, "Updated", "Applied generation %d to Deployment %s", app.Generation, desired.Name)
+	}
+}
+
+// recordStateChange records the AppDeployment becoming available or failing
+func (r *AppDeploymentReconciler) recordStateChange(app *deskreev1.AppDeployment, previousState string) {
+	if app.Status.State == previousState {
+		return
+	}
+	switch app.Status.State {
+	case StateRunning:
+		r.Recorder.Event(app, corev1.EventTypeNormal, "Available", app.Status.Message)
+	case StateFailed:
+		r.Recorder.Event(app, corev1.EventTypeWarning, "Failed", app.Status.Message)
+	}
 }
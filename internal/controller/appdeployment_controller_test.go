@@ -23,9 +23,15 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
@@ -40,6 +46,7 @@ type TestFixture struct {
 	MinReplicas    int32
 	MaxReplicas    int32
 	MemoryLimit    string
+	EnvFrom        []deskreev1.EnvFromSource
 	NamespacedName types.NamespacedName
 
 	// Test context
@@ -65,8 +72,9 @@ func NewTestFixture() *TestFixture {
 		Context:        context.Background(),
 		NamespacedName: types.NamespacedName{Name: name, Namespace: namespace},
 		Reconciler: &AppDeploymentReconciler{
-			Client: k8sClient,
-			Scheme: k8sClient.Scheme(),
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: record.NewFakeRecorder(100),
 		},
 		Timeout:  time.Second * 10,
 		Interval: time.Millisecond * 250,
@@ -109,6 +117,7 @@ func (t *TestFixture) CreateAppDeployment() *deskreev1.AppDeployment {
 									ContainerPort: 80,
 								},
 							},
+							EnvFrom: t.EnvFrom,
 						},
 					},
 				},
@@ -127,7 +136,11 @@ func (t *TestFixture) CleanupResources() {
 	appDeployment := &deskreev1.AppDeployment{}
 	err := k8sClient.Get(t.Context, t.NamespacedName, appDeployment)
 	if err == nil {
-		Expect(k8sClient.Delete(t.Context, appDeployment)).To(Succeed())
+		// No controller runs in the test environment to release the finalizer
+		if controllerutil.RemoveFinalizer(appDeployment, appDeploymentFinalizer) {
+			Expect(k8sClient.Update(t.Context, appDeployment)).To(Succeed())
+		}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(t.Context, appDeployment))).To(Succeed())
 	}
 
 	// Delete Deployment if it exists
@@ -242,6 +255,29 @@ var _ = Describe("AppDeployment Controller", func() {
 			fixture.VerifyAppDeploymentStatus("Running")
 		})
 
+		It("should record events for the creation, scaling and availability of the app", func() {
+			recorder := fixture.Reconciler.Recorder.(*record.FakeRecorder)
+
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+			Expect(recorder.Events).To(Receive(Equal("Normal Created Created Deployment " + fixture.Name)))
+
+			By("Making the replicas available")
+			Expect(fixture.UpdateDeploymentStatus(1, 1)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal Available ")))
+
+			By("Raising the minimum replicas")
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			appDeployment.Spec.MinReplicas = 2
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+			Expect(recorder.Events).To(Receive(Equal("Normal Scaled Scaled Deployment " + fixture.Name + " from 1 to 2 replicas")))
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal Updated ")))
+		})
+
 		It("should set status to Pending when deployment has zero replicas", func() {
 			By("Creating a new AppDeployment resource")
 			fixture.CreateAppDeployment()
@@ -294,4 +330,480 @@ var _ = Describe("AppDeployment Controller", func() {
 			fixture.VerifyAppDeploymentStatus("Pending")
 		})
 	})
+
+	Context("When the pods of an AppDeployment cannot start", func() {
+		It("should report the pod failure reasons while the rollout is pending", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Creating a pod of the app stuck pulling its image")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fixture.Name + "-pod",
+					Namespace: fixture.Namespace,
+					Labels:    map[string]string{"app": fixture.Name},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "container-" + fixture.Name, Image: "nginx:missing"}},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, pod)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(fixture.Context, pod))).To(Succeed())
+			})
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "container-" + fixture.Name,
+				Image: "nginx:missing",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "ImagePullBackOff",
+					Message: `Back-off pulling image "nginx:missing"`,
+				}},
+			}}
+			Expect(k8sClient.Status().Update(fixture.Context, pod)).To(Succeed())
+
+			By("Reconciling the AppDeployment again")
+			Expect(fixture.UpdateDeploymentStatus(0, 1)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the failure is reported in the status")
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(appDeployment.Status.State).To(Equal(StatePending))
+			Expect(appDeployment.Status.Message).To(ContainSubstring("ImagePullBackOff"))
+			condition := meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionPodsFailing)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("ImagePullBackOff"))
+		})
+
+		It("should set status to Failed when the rollout exceeds its progress deadline", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Marking the Deployment as past its progress deadline")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: "ReplicaSet has timed out progressing.",
+			}}
+			Expect(k8sClient.Status().Update(fixture.Context, deployment)).To(Succeed())
+
+			By("Reconciling the AppDeployment again")
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the AppDeployment status was updated to Failed")
+			fixture.VerifyAppDeploymentStatus("Failed")
+		})
+	})
+
+	Context("When deleting an AppDeployment", func() {
+		It("should add a finalizer and tear down the owned deployment before releasing it", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(appDeployment.Finalizers).To(ContainElement(appDeploymentFinalizer))
+			Expect(fixture.DeploymentExists()).To(BeTrue())
+
+			By("Deleting the AppDeployment")
+			Expect(k8sClient.Delete(fixture.Context, appDeployment)).To(Succeed())
+
+			By("Reconciling until the teardown completes")
+			Eventually(func() bool {
+				Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+				err := k8sClient.Get(fixture.Context, fixture.NamespacedName, &deskreev1.AppDeployment{})
+				return errors.IsNotFound(err)
+			}, fixture.Timeout, fixture.Interval).Should(BeTrue())
+
+			Expect(fixture.DeploymentExists()).To(BeFalse())
+		})
+
+		It("should leave a deployment it does not control in place", func() {
+			By("Creating a Deployment that is not owned by any AppDeployment")
+			replicas := int32(1)
+			orphan := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fixture.Name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": fixture.Name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "orphan", Image: fixture.Image}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, orphan)).To(Succeed())
+
+			By("Creating, reconciling and deleting the AppDeployment")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(k8sClient.Delete(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the AppDeployment is gone but the Deployment survived")
+			fixture.WaitForResourceDeletion()
+			Expect(fixture.DeploymentExists()).To(BeTrue())
+		})
+	})
+
+	Context("When a referenced ConfigMap changes", func() {
+		It("should annotate the pod template with the new hash and record the trigger", func() {
+			By("Creating the ConfigMap consumed by the app")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name + "-config", Namespace: fixture.Namespace},
+				Data:       map[string]string{"LOG_LEVEL": "info"},
+			}
+			Expect(k8sClient.Create(fixture.Context, configMap)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(fixture.Context, configMap))).To(Succeed())
+			})
+
+			By("Creating and reconciling an AppDeployment referencing it")
+			fixture.EnvFrom = []deskreev1.EnvFromSource{
+				{ConfigMapRef: &deskreev1.LocalObjectReference{Name: configMap.Name}},
+			}
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			initialHash := deployment.Spec.Template.Annotations[ConfigHashAnnotation]
+			Expect(initialHash).NotTo(BeEmpty())
+			Expect(deployment.Spec.Template.Spec.Containers[0].EnvFrom).To(HaveLen(1))
+
+			By("Changing the ConfigMap content and reconciling again")
+			configMap.Data["LOG_LEVEL"] = "debug"
+			Expect(k8sClient.Update(fixture.Context, configMap)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the pod template hash changed and the trigger was recorded")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Annotations[ConfigHashAnnotation]).NotTo(Equal(initialHash))
+
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(appDeployment.Status.LastRolloutTrigger).To(ContainSubstring("configmap/" + configMap.Name))
+			Expect(appDeployment.Status.LastRolloutTime).NotTo(BeNil())
+		})
+	})
+
+	Context("When a Deployment with the same name already exists", func() {
+		It("should adopt it and back-fill the spec when spec.adopt is set", func() {
+			By("Creating a plain Deployment")
+			replicas := int32(2)
+			existing := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fixture.Name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": fixture.Name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name:  "legacy",
+								Image: "nginx:1.27",
+								Ports: []corev1.ContainerPort{{ContainerPort: 8080}},
+								Resources: corev1.ResourceRequirements{
+									Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+								},
+							}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, existing)).To(Succeed())
+
+			By("Creating an AppDeployment that only asks for adoption")
+			appDeployment := &deskreev1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec:       deskreev1.AppDeploymentSpec{Adopt: true},
+			}
+			Expect(k8sClient.Create(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the Deployment is now controlled by the AppDeployment")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, existing)).To(Succeed())
+			Expect(metav1.IsControlledBy(existing, appDeployment)).To(BeTrue())
+
+			By("Verifying the spec was back-filled from the Deployment")
+			Expect(appDeployment.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(appDeployment.Spec.MemoryLimit).To(Equal("128Mi"))
+			Expect(appDeployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(appDeployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.27"))
+			Expect(meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionAdopted)).To(BeTrue())
+		})
+
+		It("should not back-fill the memory limit when the containers disagree on it", func() {
+			By("Creating a Deployment whose containers have different memory limits")
+			existing := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fixture.Name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": fixture.Name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "web",
+									Image: "nginx:1.27",
+									Resources: corev1.ResourceRequirements{
+										Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+									},
+								},
+								{
+									Name:  "sidecar",
+									Image: "busybox:1.36",
+									Resources: corev1.ResourceRequirements{
+										Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, existing)).To(Succeed())
+
+			By("Adopting it")
+			appDeployment := &deskreev1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec:       deskreev1.AppDeploymentSpec{Adopt: true},
+			}
+			Expect(k8sClient.Create(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying each container keeps its own limit")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(appDeployment.Spec.MemoryLimit).To(BeEmpty())
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, existing)).To(Succeed())
+			Expect(existing.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("512Mi"))
+			Expect(existing.Spec.Template.Spec.Containers[1].Resources.Limits.Memory().String()).To(Equal("32Mi"))
+		})
+
+		It("should take the fields over from the previous manager so later changes apply", func() {
+			By("Creating a plain Deployment under another field manager")
+			replicas := int32(1)
+			existing := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fixture.Name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": fixture.Name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "legacy", Image: "nginx:1.27"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, existing, client.FieldOwner("kubectl-client-side-apply"))).To(Succeed())
+
+			By("Adopting it and reconciling the back-filled spec")
+			appDeployment := &deskreev1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec:       deskreev1.AppDeploymentSpec{Adopt: true},
+			}
+			Expect(k8sClient.Create(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Changing the image through the AppDeployment")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			appDeployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.28"
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the change was applied without a conflict")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionFieldConflict)).To(BeFalse())
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, existing)).To(Succeed())
+			Expect(existing.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.28"))
+		})
+
+		It("should report a failure instead of erroring when spec.adopt is not set", func() {
+			By("Creating a plain Deployment")
+			replicas := int32(1)
+			existing := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: fixture.Name, Namespace: fixture.Namespace},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": fixture.Name}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": fixture.Name}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "legacy", Image: fixture.Image}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(fixture.Context, existing)).To(Succeed())
+
+			By("Creating and reconciling a regular AppDeployment with the same name")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the AppDeployment is Failed and the Deployment untouched")
+			fixture.VerifyAppDeploymentStatus(StateFailed)
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, existing)).To(Succeed())
+			Expect(metav1.GetControllerOf(existing)).To(BeNil())
+		})
+	})
+
+	Context("When another field manager changes a field the controller owns", func() {
+		It("should report a field conflict instead of overwriting it", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(deployment.ManagedFields).To(ContainElement(HaveField("Manager", FieldManager)))
+
+			By("Scaling the Deployment under a different field manager")
+			replicas := int32(5)
+			deployment.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("kubectl-edit"))).To(Succeed())
+
+			By("Changing the AppDeployment spec so the controller applies again")
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			appDeployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.27"
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the conflict is reported and the other manager's value kept")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionFieldConflict)).To(BeTrue())
+
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(5)))
+		})
+	})
+
+	Context("When an autoscaler scales the Deployment", func() {
+		It("should keep a replica count between minReplicas and maxReplicas", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Scaling the Deployment to maxReplicas like an autoscaler would")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			replicas := fixture.MaxReplicas
+			deployment.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("horizontal-pod-autoscaler"))).To(Succeed())
+
+			By("Changing the AppDeployment spec so the controller applies again")
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			appDeployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.27"
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the new image applied without resetting the replicas")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionFieldConflict)).To(BeFalse())
+
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.27"))
+			Expect(*deployment.Spec.Replicas).To(Equal(fixture.MaxReplicas))
+		})
+	})
+
+	Context("When the generated Deployment is edited out of band", func() {
+		It("should report the drifted fields with the report policy", func() {
+			By("Creating and reconciling a new AppDeployment resource")
+			fixture.CreateAppDeployment()
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Changing the image like kubectl edit would")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			deployment.Spec.Template.Spec.Containers[0].Image = "nginx:hotfix"
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("kubectl-edit"))).To(Succeed())
+
+			By("Reconciling the AppDeployment again")
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the drift is reported and the live value kept")
+			appDeployment := &deskreev1.AppDeployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appDeployment.Status.Conditions, ConditionDrifted)).To(BeTrue())
+			Expect(appDeployment.Status.Drift).To(ContainElement(HaveField("Path", "spec.template.spec.containers[container-test-app].image")))
+
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:hotfix"))
+		})
+
+		It("should re-apply the desired state with the revert policy", func() {
+			By("Creating and reconciling an AppDeployment with the revert policy")
+			appDeployment := fixture.CreateAppDeployment()
+			appDeployment.Spec.DriftPolicy = deskreev1.DriftPolicyRevert
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Changing the image like kubectl edit would")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			deployment.Spec.Template.Spec.Containers[0].Image = "nginx:hotfix"
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("kubectl-edit"))).To(Succeed())
+
+			By("Reconciling the AppDeployment again")
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the image was reverted")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal(fixture.Image))
+
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionDrifted).Reason).To(Equal("DriftReverted"))
+		})
+
+		It("should only treat replicas outside the min/max range as drift", func() {
+			By("Creating and reconciling an AppDeployment with the revert policy")
+			appDeployment := fixture.CreateAppDeployment()
+			appDeployment.Spec.DriftPolicy = deskreev1.DriftPolicyRevert
+			Expect(k8sClient.Update(fixture.Context, appDeployment)).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Scaling the Deployment within the range like an autoscaler would")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			replicas := fixture.MaxReplicas
+			deployment.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("horizontal-pod-autoscaler"))).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the scaling is neither reported nor reverted")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(appDeployment.Status.Drift).To(BeEmpty())
+			Expect(meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionDrifted).Reason).To(Equal("InSync"))
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(fixture.MaxReplicas))
+
+			By("Scaling the Deployment past maxReplicas")
+			replicas = fixture.MaxReplicas + 3
+			deployment.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(fixture.Context, deployment, client.FieldOwner("kubectl-edit"))).To(Succeed())
+			Expect(fixture.ReconcileAppDeployment()).To(Succeed())
+
+			By("Verifying the replicas were brought back to maxReplicas")
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(fixture.MaxReplicas))
+			Expect(k8sClient.Get(fixture.Context, fixture.NamespacedName, appDeployment)).To(Succeed())
+			Expect(meta.FindStatusCondition(appDeployment.Status.Conditions, ConditionDrifted).Reason).To(Equal("DriftReverted"))
+		})
+	})
 })
@@ -0,0 +1,153 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// ConditionDrifted reports that the generated resources were changed outside the controller
+const ConditionDrifted = "Drifted"
+
+// diffDeployment compares the fields the controller sets on a Deployment against the live
+// object and returns every field whose value differs. Fields the controller does not set,
+// such as server-side defaults, are ignored.
+func diffDeployment(desired, live *appsv1.Deployment) []deskreev1.FieldDiff {
+	resource := fmt.Sprintf("Deployment/%s", desired.Name)
+	var diffs []deskreev1.FieldDiff
+	add := func(path, desiredValue, liveValue string) {
+		if desiredValue != liveValue {
+			diffs = append(diffs, deskreev1.FieldDiff{Resource: resource, Path: path, Desired: desiredValue, Live: liveValue})
+		}
+	}
+
+	for k, v := range desired.Labels {
+		add(fmt.Sprintf("metadata.labels[%s]", k), v, live.Labels[k])
+	}
+
+	// The desired replicas follow the live count within the min/max range (see desiredReplicas),
+	// so scaling by an autoscaler is not drift; only a count outside the range is
+	add("spec.replicas", int32PtrString(desired.Spec.Replicas), int32PtrString(live.Spec.Replicas))
+
+	for k, v := range desired.Spec.Template.Labels {
+		add(fmt.Sprintf("spec.template.metadata.labels[%s]", k), v, live.Spec.Template.Labels[k])
+	}
+	for k, v := range desired.Spec.Template.Annotations {
+		add(fmt.Sprintf("spec.template.metadata.annotations[%s]", k), v, live.Spec.Template.Annotations[k])
+	}
+
+	liveContainers := make(map[string]corev1.Container, len(live.Spec.Template.Spec.Containers))
+	for _, c := range live.Spec.Template.Spec.Containers {
+		liveContainers[c.Name] = c
+	}
+	for _, want := range desired.Spec.Template.Spec.Containers {
+		prefix := fmt.Sprintf("spec.template.spec.containers[%s]", want.Name)
+		got, ok := liveContainers[want.Name]
+		if !ok {
+			add(prefix, "present", "missing")
+			continue
+		}
+		add(prefix+".image", want.Image, got.Image)
+		add(prefix+".resources.limits.memory", quantityString(want.Resources.Limits, corev1.ResourceMemory),
+			quantityString(got.Resources.Limits, corev1.ResourceMemory))
+		add(prefix+".ports", portsString(want.Ports), portsString(got.Ports))
+		add(prefix+".envFrom", envFromString(want.EnvFrom), envFromString(got.EnvFrom))
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// PreviewDeployment returns the fields of the live Deployment that applying app would change.
+// The configuration hash of live is kept, as it follows the content of the ConfigMaps and
+// Secrets rather than the AppDeployment. When live is nil, every field the controller sets
+// is reported with an empty live value.
+func PreviewDeployment(app *deskreev1.AppDeployment, live *appsv1.Deployment) ([]deskreev1.FieldDiff, error) {
+	configHash := ""
+	if live != nil {
+		configHash = live.Spec.Template.Annotations[ConfigHashAnnotation]
+	}
+
+	desired, err := desiredDeployment(app, live, DeploymentName(app), configHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if live == nil {
+		// Name the containers so that their fields are listed one by one
+		live = &appsv1.Deployment{}
+		for _, container := range desired.Spec.Template.Spec.Containers {
+			live.Spec.Template.Spec.Containers = append(live.Spec.Template.Spec.Containers, corev1.Container{Name: container.Name})
+		}
+	}
+	return diffDeployment(desired, live), nil
+}
+
+// describeDrift summarises the drifted field paths for a condition message
+func describeDrift(diffs []deskreev1.FieldDiff) string {
+	paths := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		paths = append(paths, fmt.Sprintf("%s %s", d.Resource, d.Path))
+	}
+	return strings.Join(paths, ", ")
+}
+
+func int32PtrString(v *int32) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+// quantityString returns the canonical form of a resource quantity so that equal amounts
+// written differently (e.g. 1Gi and 1024Mi) compare equal
+func quantityString(resources corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := resources[name]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d", q.Value())
+}
+
+func portsString(ports []corev1.ContainerPort) string {
+	values := make([]string, 0, len(ports))
+	for _, p := range ports {
+		values = append(values, fmt.Sprintf("%d", p.ContainerPort))
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func envFromString(sources []corev1.EnvFromSource) string {
+	values := make([]string, 0, len(sources))
+	for _, s := range sources {
+		switch {
+		case s.ConfigMapRef != nil:
+			values = append(values, "configmap/"+s.ConfigMapRef.Name)
+		case s.SecretRef != nil:
+			values = append(values, "secret/"+s.SecretRef.Name)
+		}
+	}
+	return strings.Join(values, ",")
+}
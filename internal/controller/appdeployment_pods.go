@@ -0,0 +1,133 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionPodsFailing reports that pods of the app cannot start or keep crashing
+const ConditionPodsFailing = "PodsFailing"
+
+// rolloutCheckInterval is how often a Pending app is reconciled to pick up pod failures,
+// which do not change the Deployment and so trigger no reconcile of their own
+const rolloutCheckInterval = 15 * time.Second
+
+// podFailureReasons are the container waiting reasons that need a change to the app or the
+// cluster rather than more time
+var podFailureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// podFailure is a pod of the app that cannot start or keeps crashing
+type podFailure struct {
+	Pod     string
+	Reason  string
+	Message string
+}
+
+func (f podFailure) String() string {
+	if f.Message == "" {
+		return fmt.Sprintf("pod %s: %s", f.Pod, f.Reason)
+	}
+	return fmt.Sprintf("pod %s: %s: %s", f.Pod, f.Reason, f.Message)
+}
+
+// podFailures lists the failing pods of a Deployment
+func (r *AppDeploymentReconciler) podFailures(ctx context.Context, deployment *appsv1.Deployment) ([]podFailure, error) {
+	if deployment.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Deployment selector: %v", err)
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(deployment.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	var failures []podFailure
+	for i := range pods.Items {
+		if failure, ok := failureOf(&pods.Items[i]); ok {
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+// failureOf returns why a pod cannot run: a container stuck on one of podFailureReasons, with
+// the reason of its last crash, or a scheduler that found no node for it
+func failureOf(pod *corev1.Pod) (podFailure, bool) {
+	if !pod.DeletionTimestamp.IsZero() {
+		return podFailure{}, false
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil || !podFailureReasons[waiting.Reason] {
+			continue
+		}
+		failure := podFailure{Pod: pod.Name, Reason: waiting.Reason, Message: waiting.Message}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil && waiting.Reason == "CrashLoopBackOff" {
+			failure.Message = fmt.Sprintf("container %s last exited with code %d (%s)", status.Name, terminated.ExitCode, terminated.Reason)
+		}
+		return failure, true
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			return podFailure{Pod: pod.Name, Reason: condition.Reason, Message: condition.Message}, true
+		}
+	}
+
+	return podFailure{}, false
+}
+
+// describePodFailures summarizes failing pods for a status message
+func describePodFailures(failures []podFailure) string {
+	if len(failures) == 1 {
+		return failures[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more failing pods)", failures[0], len(failures)-1)
+}
+
+// progressDeadlineExceeded returns the message of a Deployment that gave up on its rollout
+func progressDeadlineExceeded(deployment *appsv1.Deployment) (string, bool) {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded" {
+			return condition.Message, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+const (
+	// ConditionPreDeleteHook reports the outcome of the pre-delete hook
+	ConditionPreDeleteHook = "PreDeleteHookCompleted"
+
+	// defaultPreDeleteHookTimeoutSeconds is used when the hook does not set its own timeout
+	defaultPreDeleteHookTimeoutSeconds int32 = 300
+	// teardownRequeueInterval is how often teardown is re-checked while waiting on the hook or a deletion
+	teardownRequeueInterval = 5 * time.Second
+)
+
+// reconcileDelete runs the teardown sequence for an AppDeployment that is being deleted.
+// The pre-delete hook runs first while the application is still serving, then the Deployment
+// is removed, then the hook Job, and only then is the finalizer released. Resources that are
+// not controlled by this AppDeployment are never deleted.
+func (r *AppDeploymentReconciler) reconcileDelete(ctx context.Context, app *deskreev1.AppDeployment) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(app, appDeploymentFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if app.Spec.PreDeleteHook != nil {
+		done, err := r.runPreDeleteHook(ctx, app)
+		if err != nil {
+			logger.Error(err, "Failed to run pre-delete hook")
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: teardownRequeueInterval},
+				r.setTerminating(ctx, app, "Waiting for pre-delete hook to complete")
+		}
+	}
+
+	deploymentName := DeploymentName(app)
+
+	gone, err := r.deleteOwned(ctx, app, &appsv1.Deployment{}, types.NamespacedName{Name: deploymentName, Namespace: app.Namespace})
+	if err != nil {
+		logger.Error(err, "Failed to delete Deployment", "DeploymentName", deploymentName)
+		return ctrl.Result{}, err
+	}
+	if !gone {
+		return ctrl.Result{RequeueAfter: teardownRequeueInterval},
+			r.setTerminating(ctx, app, fmt.Sprintf("Deleting deployment %s", deploymentName))
+	}
+
+	gone, err = r.deleteOwned(ctx, app, &batchv1.Job{}, types.NamespacedName{Name: preDeleteJobName(app), Namespace: app.Namespace})
+	if err != nil {
+		logger.Error(err, "Failed to delete pre-delete hook Job")
+		return ctrl.Result{}, err
+	}
+	if !gone {
+		return ctrl.Result{RequeueAfter: teardownRequeueInterval},
+			r.setTerminating(ctx, app, "Deleting pre-delete hook job")
+	}
+
+	controllerutil.RemoveFinalizer(app, appDeploymentFinalizer)
+	if err := r.Update(ctx, app); err != nil {
+		logger.Error(err, "Failed to remove finalizer from AppDeployment")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("AppDeployment teardown complete")
+	return ctrl.Result{}, nil
+}
+
+// setTerminating records teardown progress on the AppDeployment status
+func (r *AppDeploymentReconciler) setTerminating(ctx context.Context, app *deskreev1.AppDeployment, message string) error {
+	app.Status.State = StateTerminating
+	app.Status.Message = message
+	return r.Status().Update(ctx, app)
+}
+
+// runPreDeleteHook makes sure the hook Job exists and reports whether it has finished.
+// A failed or timed out hook does not block teardown; the outcome is recorded as a condition.
+func (r *AppDeploymentReconciler) runPreDeleteHook(ctx context.Context, app *deskreev1.AppDeployment) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	if meta.FindStatusCondition(app.Status.Conditions, ConditionPreDeleteHook) != nil {
+		return true, nil
+	}
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: preDeleteJobName(app), Namespace: app.Namespace}, job)
+	if errors.IsNotFound(err) {
+		logger.Info("Starting pre-delete hook", "Job", preDeleteJobName(app))
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, "PreDeleteHook", "Running pre-delete hook Job %s", preDeleteJobName(app))
+		return false, r.Create(ctx, newPreDeleteJob(app))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !metav1.IsControlledBy(job, app) {
+		return true, r.setPreDeleteHookCondition(ctx, app, metav1.ConditionFalse, "JobNameConflict",
+			fmt.Sprintf("Job %s exists but is not owned by this AppDeployment", job.Name))
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, r.setPreDeleteHookCondition(ctx, app, metav1.ConditionTrue, "Succeeded",
+				"Pre-delete hook completed successfully")
+		case batchv1.JobFailed:
+			// The Job fails with DeadlineExceeded once the hook timeout passes
+			reason, outcome := "Failed", "failed"
+			if c.Reason == batchv1.JobReasonDeadlineExceeded {
+				reason, outcome = "TimedOut", "timed out"
+			}
+			logger.Info("Pre-delete hook "+outcome+", continuing teardown", "Reason", c.Reason)
+			r.Recorder.Eventf(app, corev1.EventTypeWarning, "PreDeleteHook"+reason, "Pre-delete hook %s, continuing teardown: %s %s", outcome, c.Reason, c.Message)
+			return true, r.setPreDeleteHookCondition(ctx, app, metav1.ConditionFalse, reason,
+				fmt.Sprintf("Pre-delete hook %s: %s %s", outcome, c.Reason, c.Message))
+		}
+	}
+
+	return false, nil
+}
+
+// setPreDeleteHookCondition records the outcome of the pre-delete hook and saves it right away,
+// so the status explains the teardown even when a later step fails before the next status write
+func (r *AppDeploymentReconciler) setPreDeleteHookCondition(ctx context.Context, app *deskreev1.AppDeployment, status metav1.ConditionStatus, reason, message string) error {
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:    ConditionPreDeleteHook,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	return r.Status().Update(ctx, app)
+}
+
+// deleteOwned deletes the object at key if it is controlled by the AppDeployment and reports
+// whether it is gone. Objects owned by someone else are left in place and reported as gone.
+func (r *AppDeploymentReconciler) deleteOwned(ctx context.Context, app *deskreev1.AppDeployment, obj client.Object, key types.NamespacedName) (bool, error) {
+	if err := r.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if !metav1.IsControlledBy(obj, app) {
+		log.FromContext(ctx).Info("Leaving resource that is not controlled by this AppDeployment",
+			"Kind", fmt.Sprintf("%T", obj), "Name", key.Name)
+		return true, nil
+	}
+
+	if obj.GetDeletionTimestamp().IsZero() {
+		if err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// preDeleteJobName returns the name of the Job running the pre-delete hook
+func preDeleteJobName(app *deskreev1.AppDeployment) string {
+	return fmt.Sprintf("%s-pre-delete", app.Name)
+}
+
+// newPreDeleteJob builds the Job that runs the pre-delete hook of an AppDeployment
+func newPreDeleteJob(app *deskreev1.AppDeployment) *batchv1.Job {
+	hook := app.Spec.PreDeleteHook
+
+	timeout := int64(hook.TimeoutSeconds)
+	if timeout <= 0 {
+		timeout = int64(defaultPreDeleteHookTimeoutSeconds)
+	}
+	backoffLimit := int32(0)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      preDeleteJobName(app),
+			Namespace: app.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/instance":   app.Name,
+				"app.kubernetes.io/component":  "pre-delete-hook",
+				"app.kubernetes.io/managed-by": "appdeployment-controller",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(app, deskreev1.GroupVersion.WithKind("AppDeployment")),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &timeout,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "pre-delete",
+							Image:   hook.Image,
+							Command: hook.Command,
+							Env: []corev1.EnvVar{
+								{Name: "APP_NAME", Value: app.Name},
+								{Name: "APP_NAMESPACE", Value: app.Namespace},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -17,15 +17,42 @@ limitations under the License.
 package auth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/espinozasenior/go-assesstment.git/pkg/client"
+	"github.com/espinozasenior/go-assesstment.git/pkg/oidc"
 )
 
+// refreshLeeway is how long before its expiry an access token is refreshed
+const refreshLeeway = 30 * time.Second
+
+// TokenEnvVar names the environment variable whose token, typically an API key, is used
+// instead of the stored login, for non-interactive use such as CI pipelines
+const TokenEnvVar = "GO_ASSESSMENT_TOKEN"
+
+// ErrSessionExpired is returned when the access token expired and cannot be refreshed
+var ErrSessionExpired = errors.New("session expired, please run 'go-assessment login' again")
+
 // Config represents the authentication configuration
 type Config struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// ExpiresAt is the expiry of Token
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// OIDC is set when the tokens come from an external OpenID provider rather than the API server
+	OIDC *OIDCConfig `json:"oidc,omitempty"`
+}
+
+// OIDCConfig identifies the OpenID provider and client the stored tokens were issued by
+type OIDCConfig struct {
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"clientID"`
 }
 
 // GetConfigDir returns the directory where the config file is stored
@@ -49,8 +76,8 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, "config.json"), nil
 }
 
-// SaveToken saves the authentication token to the config file
-func SaveToken(token string) error {
+// SaveConfig saves the authentication configuration to the config file
+func SaveConfig(config Config) error {
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return err
@@ -66,10 +93,6 @@ func SaveToken(token string) error {
 		return err
 	}
 
-	config := Config{
-		Token: token,
-	}
-
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error marshaling config: %v", err)
@@ -82,43 +105,192 @@ func SaveToken(token string) error {
 	return nil
 }
 
-// GetToken retrieves the authentication token from the config file
-func GetToken() (string, error) {
+// LoadConfig reads the authentication configuration from the config file
+func LoadConfig() (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", fmt.Errorf("not logged in, please run 'go-assessment login' first")
+			return nil, fmt.Errorf("not logged in, please run 'go-assessment login' first")
 		}
-		return "", fmt.Errorf("error reading config file: %v", err)
+		return nil, fmt.Errorf("error reading config file: %v", err)
 	}
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
-		return "", fmt.Errorf("error unmarshaling config: %v", err)
+		return nil, fmt.Errorf("error unmarshaling config: %v", err)
 	}
 
 	if config.Token == "" {
-		return "", fmt.Errorf("token not found, please run 'go-assessment login' first")
+		return nil, fmt.Errorf("token not found, please run 'go-assessment login' first")
+	}
+
+	return &config, nil
+}
+
+// ClearConfig removes the stored tokens
+func ClearConfig() error {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing config file: %v", err)
+	}
+	return nil
+}
+
+// TokenFromEnv returns the token set in TokenEnvVar, if any
+func TokenFromEnv() (string, bool) {
+	token := os.Getenv(TokenEnvVar)
+	return token, token != ""
+}
+
+// GetToken retrieves the authentication token from TokenEnvVar or else the config file,
+// failing when the stored token has expired
+func GetToken() (string, error) {
+	if token, ok := TokenFromEnv(); ok {
+		return token, nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if !config.ExpiresAt.IsZero() && time.Now().After(config.ExpiresAt) {
+		return "", ErrSessionExpired
 	}
 
 	return config.Token, nil
 }
 
-// Login simulates authentication and stores the token
-func Login(username, password string) error {
-	// In a real application, this would make an API call to authenticate
-	// For this assessment, we'll simulate authentication by generating a token
+// TokenSource supplies the stored access token to a client, refreshing it against the
+// API server shortly before it expires
+type TokenSource struct {
+	ServerURL string
+}
+
+// NewTokenSource returns a token source refreshing tokens against the API server at serverURL
+func NewTokenSource(serverURL string) *TokenSource {
+	return &TokenSource{ServerURL: serverURL}
+}
+
+// Token implements client.TokenSource
+func (t *TokenSource) Token() (string, error) {
+	if token, ok := TokenFromEnv(); ok {
+		return token, nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if config.ExpiresAt.IsZero() || time.Until(config.ExpiresAt) > refreshLeeway {
+		return config.Token, nil
+	}
+
+	if config.RefreshToken == "" {
+		return "", ErrSessionExpired
+	}
+
+	if config.OIDC != nil {
+		return refreshOIDC(config)
+	}
+
+	tokens, err := client.NewClient(t.ServerURL, "").Refresh(config.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+
+	if err := SaveTokens(tokens); err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// SaveTokens stores the tokens handed out by the API server
+func SaveTokens(tokens *client.TokenResponse) error {
+	return SaveConfig(Config{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	})
+}
+
+// Login authenticates against the API server at serverURL and stores the tokens it returns
+func Login(serverURL, username, password string) error {
 	if username == "" || password == "" {
 		return fmt.Errorf("username and password are required")
 	}
 
-	// Generate a simple token (in a real app, this would come from the server)
-	token := fmt.Sprintf("simulated-token-%s-%s", username, password)
+	tokens, err := client.NewClient(serverURL, "").Login(username, password)
+	if err != nil {
+		return err
+	}
+
+	return SaveTokens(tokens)
+}
+
+// LoginOIDC logs in through the device authorization grant of an OpenID provider and stores
+// the ID token it returns. prompt is called with the code the user has to enter.
+func LoginOIDC(ctx context.Context, issuer, clientID string, prompt func(*oidc.DeviceAuthorization)) error {
+	provider, err := oidc.Discover(ctx, nil, issuer)
+	if err != nil {
+		return err
+	}
+
+	flow := &oidc.DeviceFlow{Provider: provider, ClientID: clientID}
+	authorization, err := flow.Start(ctx)
+	if err != nil {
+		return err
+	}
+	prompt(authorization)
+
+	tokens, err := flow.Wait(ctx, authorization)
+	if err != nil {
+		return err
+	}
+
+	return saveOIDCTokens(tokens, &OIDCConfig{Issuer: issuer, ClientID: clientID})
+}
+
+// refreshOIDC renews an expiring ID token with the provider refresh token
+func refreshOIDC(config *Config) (string, error) {
+	ctx := context.Background()
+	provider, err := oidc.Discover(ctx, nil, config.OIDC.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	tokens, err := oidc.Refresh(ctx, nil, provider, config.OIDC.ClientID, config.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+
+	if err := saveOIDCTokens(tokens, config.OIDC); err != nil {
+		return "", err
+	}
+	return tokens.IDToken, nil
+}
+
+// saveOIDCTokens stores the ID token of a provider response as the access token
+func saveOIDCTokens(tokens *oidc.Tokens, provider *OIDCConfig) error {
+	expiresAt, err := oidc.UnverifiedExpiry(tokens.IDToken)
+	if err != nil {
+		return err
+	}
 
-	return SaveToken(token)
+	return SaveConfig(Config{
+		Token:        tokens.IDToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    expiresAt,
+		OIDC:         provider,
+	})
 }
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/url"
+	"time"
+)
+
+// APIKey represents a long-lived API key as listed by the server
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Owner      string     `json:"owner"`
+	Namespaces []string   `json:"namespaces"`
+	Verbs      []string   `json:"verbs"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the scope of a new API key
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name"`
+	Namespaces []string   `json:"namespaces"`
+	Verbs      []string   `json:"verbs"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreatedAPIKey represents a new API key, the only response carrying the key itself
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey creates an API key owned by the caller
+func (c *Client) CreateAPIKey(req CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	var key CreatedAPIKey
+	if err := c.do("POST", c.BaseURL+"/api/v1/apikeys", "application/json", req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys lists the API keys of the caller
+func (c *Client) ListAPIKeys() ([]APIKey, error) {
+	var list struct {
+		Items []APIKey `json:"items"`
+	}
+	if err := c.do("GET", c.BaseURL+"/api/v1/apikeys", "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// RevokeAPIKey deletes an API key of the caller
+func (c *Client) RevokeAPIKey(id string) error {
+	return c.do("DELETE", c.BaseURL+"/api/v1/apikeys/"+url.PathEscape(id), "", nil, nil)
+}
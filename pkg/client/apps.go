@@ -0,0 +1,192 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// defaultNamespace is used for /api/v1 requests when the client has no namespace set
+const defaultNamespace = "default"
+
+// appsPath returns the /api/v1 path of the apps collection, or of a single app when name is set
+func (c *Client) appsPath(name string) string {
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	path := c.BaseURL + "/api/v1/namespaces/" + url.PathEscape(namespace) + "/apps"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// do sends an authenticated JSON request and decodes a successful JSON response into out when it is not nil
+func (c *Client) do(method, endpoint, contentType string, body interface{}, out interface{}) error {
+	return c.doIfMatch(method, endpoint, contentType, "", body, out)
+}
+
+// doIfMatch is do with an If-Match header making the request conditional on the entity tag
+// ifMatch, when it is set
+func (c *Client) doIfMatch(method, endpoint, contentType, ifMatch string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %v", err)
+		}
+		reader = bytes.NewBuffer(data)
+	}
+
+	request, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if ifMatch != "" {
+		request.Header.Set("If-Match", ifMatch)
+	}
+	if err := c.authorize(request); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("error closing response body: %v\n", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+
+	return nil
+}
+
+// ListApps lists the AppDeployments in the client namespace
+func (c *Client) ListApps() ([]deskreev1.AppDeployment, error) {
+	var list deskreev1.AppDeploymentList
+	if err := c.do("GET", c.appsPath(""), "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// GetApp retrieves the full spec and status of an AppDeployment
+func (c *Client) GetApp(name string) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.do("GET", c.appsPath(name), "", nil, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// CreateApp creates an AppDeployment from a deploy request
+func (c *Client) CreateApp(req DeployRequest) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.do("POST", c.appsPath(""), "application/json", req, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// UpdateApp replaces the spec of an existing AppDeployment with a deploy request
+func (c *Client) UpdateApp(req DeployRequest) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.do("PUT", c.appsPath(req.Name), "application/json", req, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// PatchApp applies a JSON merge patch, in the deploy request form, to an AppDeployment
+func (c *Client) PatchApp(name string, patch map[string]interface{}) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.do("PATCH", c.appsPath(name), "application/merge-patch+json", patch, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// DiffResponse lists what applying a deploy request would change; in each change, Desired is
+// the value the request produces and Live the current one
+type DiffResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Exists is false when the request would create the app
+	Exists  bool        `json:"exists"`
+	Changes []FieldDiff `json:"changes,omitempty"`
+}
+
+// DiffApp compares a deploy request against the live AppDeployment and Deployment, changing nothing
+func (c *Client) DiffApp(req DeployRequest) (*DiffResponse, error) {
+	var diff DiffResponse
+	if err := c.do("POST", c.appsPath(req.Name)+"/diff", "application/json", req, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// DeleteApp deletes an AppDeployment
+func (c *Client) DeleteApp(name string) error {
+	return c.do("DELETE", c.appsPath(name), "", nil, nil)
+}
+
+// AppEvent represents a Kubernetes Event about an app, its Deployment, ReplicaSets or pods
+type AppEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Count   int32     `json:"count,omitempty"`
+}
+
+// GetAppEvents retrieves the events of an app and the objects it runs on, oldest first
+func (c *Client) GetAppEvents(name string) ([]AppEvent, error) {
+	var list struct {
+		Items []AppEvent `json:"items"`
+	}
+	if err := c.do("GET", c.appsPath(name)+"/events", "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
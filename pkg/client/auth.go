@@ -0,0 +1,109 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// LoginRequest represents the credentials sent to the login endpoint
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// TokenResponse represents the tokens handed out by the login endpoint
+type TokenResponse struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	TokenType    string    `json:"tokenType"`
+	ExpiresIn    int64     `json:"expiresIn"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// WhoAmIResponse represents the caller identity returned by the whoami endpoint
+type WhoAmIResponse struct {
+	Username  string    `json:"username"`
+	Groups    []string  `json:"groups,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// APIKeyID is set when the client token is an API key
+	APIKeyID string `json:"apiKeyID,omitempty"`
+}
+
+// Login exchanges a username and password for an access and a refresh token
+func (c *Client) Login(username, password string) (*TokenResponse, error) {
+	return c.requestTokens("/auth/login", "login", LoginRequest{Username: username, Password: password})
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair
+func (c *Client) Refresh(refreshToken string) (*TokenResponse, error) {
+	return c.requestTokens("/auth/refresh", "refresh", map[string]string{"refreshToken": refreshToken})
+}
+
+// Logout revokes the session of the client token on the server
+func (c *Client) Logout() error {
+	return c.do("POST", c.BaseURL+"/auth/logout", "", nil, nil)
+}
+
+// WhoAmI retrieves the identity the server associates with the client token
+func (c *Client) WhoAmI() (*WhoAmIResponse, error) {
+	var whoami WhoAmIResponse
+	if err := c.do("GET", c.BaseURL+"/auth/whoami", "", nil, &whoami); err != nil {
+		return nil, err
+	}
+	return &whoami, nil
+}
+
+// requestTokens posts body to one of the unauthenticated token endpoints
+func (c *Client) requestTokens(path, action string, body interface{}) (*TokenResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	request, err := http.NewRequest("POST", c.BaseURL+path, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("error closing response body: %v\n", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s failed: %w", action, decodeError(resp))
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+
+	return &tokenResp, nil
+}
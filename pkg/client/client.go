@@ -20,8 +20,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -30,6 +30,16 @@ type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Token      string
+	// TokenSource, when set, supplies the token of every request instead of Token, so that
+	// expired tokens can be refreshed transparently
+	TokenSource TokenSource
+	// Namespace scopes every request; the server default namespace is used when empty
+	Namespace string
+}
+
+// TokenSource returns a valid access token, refreshing it when needed
+type TokenSource interface {
+	Token() (string, error)
 }
 
 // DeployRequest represents the request body for deploying an application
@@ -39,12 +49,58 @@ type DeployRequest struct {
 	MemoryLimit string `json:"memoryLimit"`
 	MinReplicas int32  `json:"minReplicas"`
 	MaxReplicas int32  `json:"maxReplicas"`
+	// ConfigMaps and Secrets are exposed to the container as environment variables
+	ConfigMaps []string `json:"configMaps,omitempty"`
+	Secrets    []string `json:"secrets,omitempty"`
+}
+
+// DeployOptions tunes a deploy request
+type DeployOptions struct {
+	// DryRun has the cluster validate the request without changing anything
+	DryRun bool
+	// IdempotencyKey, when set, makes retries of the request with the same key get the outcome
+	// of the first one instead of being applied again
+	IdempotencyKey string
+}
+
+// DeployResponse represents the response from the deploy endpoint
+type DeployResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Created is false when the deploy updated an existing app
+	Created bool `json:"-"`
+	// Replayed is set when the response is the remembered outcome of an earlier request with
+	// the same idempotency key
+	Replayed bool `json:"-"`
+}
+
+// ImportRequest represents the request body for adopting an existing Deployment
+type ImportRequest struct {
+	Name       string `json:"name,omitempty"`
+	Deployment string `json:"deployment"`
 }
 
 // StatusResponse represents the response from the status endpoint
 type StatusResponse struct {
 	Status   string `json:"status"`
 	Replicas int32  `json:"replicas"`
+	Message  string `json:"message,omitempty"`
+}
+
+// FieldDiff describes a field of a generated resource whose live value differs from the desired one
+type FieldDiff struct {
+	Resource string `json:"resource"`
+	Path     string `json:"path"`
+	Desired  string `json:"desired,omitempty"`
+	Live     string `json:"live,omitempty"`
+}
+
+// DriftResponse represents the response from the drift endpoint
+type DriftResponse struct {
+	Name    string      `json:"name"`
+	Policy  string      `json:"policy"`
+	Drifted bool        `json:"drifted"`
+	Fields  []FieldDiff `json:"fields,omitempty"`
 }
 
 // NewClient creates a new API client
@@ -58,20 +114,115 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
-// Deploy sends a request to deploy an application
+// authorize sets the bearer token of a request
+func (c *Client) authorize(request *http.Request) error {
+	token := c.Token
+	if c.TokenSource != nil {
+		var err error
+		if token, err = c.TokenSource.Token(); err != nil {
+			return err
+		}
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return nil
+}
+
+// endpoint returns the URL of an API path with the given query, scoped to the client namespace
+func (c *Client) endpoint(path string, query url.Values) string {
+	if c.Namespace != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("namespace", c.Namespace)
+	}
+
+	endpoint := c.BaseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	return endpoint
+}
+
+// Deploy sends a request to deploy an application, creating it or updating an existing app
+// of the same name
 func (c *Client) Deploy(req DeployRequest) error {
+	_, err := c.DeployWithOptions(req, DeployOptions{})
+	return err
+}
+
+// DeployDryRun sends a deploy request as a server-side dry run: it is validated and admitted
+// by the cluster as Deploy would be, but nothing is changed
+func (c *Client) DeployDryRun(req DeployRequest) error {
+	_, err := c.DeployWithOptions(req, DeployOptions{DryRun: true})
+	return err
+}
+
+// DeployWithOptions sends a deploy request and reports whether it created or updated the app
+func (c *Client) DeployWithOptions(req DeployRequest, opts DeployOptions) (*DeployResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	var query url.Values
+	if opts.DryRun {
+		query = url.Values{"dryRun": {"All"}}
+	}
+	request, err := http.NewRequest("POST", c.endpoint("/deploy", query), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if opts.IdempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", opts.IdempotencyKey)
+	}
+	if err := c.authorize(request); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("error closing response body: %v\n", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+
+	deployResp := DeployResponse{
+		Created:  resp.StatusCode == http.StatusCreated,
+		Replayed: resp.Header.Get("Idempotent-Replayed") == "true",
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&deployResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+
+	return &deployResp, nil
+}
+
+// Import asks the API to adopt an existing Deployment into AppDeployment management
+func (c *Client) Import(req ImportRequest) error {
 	data, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	request, err := http.NewRequest("POST", fmt.Sprintf("%s/deploy", c.BaseURL), bytes.NewBuffer(data))
+	request, err := http.NewRequest("POST", c.endpoint("/import", nil), bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
 
 	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	if err := c.authorize(request); err != nil {
+		return err
+	}
 
 	resp, err := c.HTTPClient.Do(request)
 	if err != nil {
@@ -84,8 +235,7 @@ func (c *Client) Deploy(req DeployRequest) error {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("deployment failed with status %d: %s", resp.StatusCode, string(body))
+		return decodeError(resp)
 	}
 
 	return nil
@@ -93,12 +243,14 @@ func (c *Client) Deploy(req DeployRequest) error {
 
 // GetStatus retrieves the status of a deployment
 func (c *Client) GetStatus(name string) (*StatusResponse, error) {
-	request, err := http.NewRequest("GET", fmt.Sprintf("%s/status/%s", c.BaseURL, name), nil)
+	request, err := http.NewRequest("GET", c.endpoint("/status/"+url.PathEscape(name), nil), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	if err := c.authorize(request); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(request)
 	if err != nil {
@@ -111,8 +263,7 @@ func (c *Client) GetStatus(name string) (*StatusResponse, error) {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("status request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, decodeError(resp)
 	}
 
 	var statusResp StatusResponse
@@ -123,14 +274,49 @@ func (c *Client) GetStatus(name string) (*StatusResponse, error) {
 	return &statusResp, nil
 }
 
+// GetDrift retrieves the fields of a deployment that were changed outside the platform
+func (c *Client) GetDrift(name string) (*DriftResponse, error) {
+	request, err := http.NewRequest("GET", c.endpoint("/apps/"+url.PathEscape(name)+"/drift", nil), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	if err := c.authorize(request); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("error closing response body: %v\n", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+
+	var driftResp DriftResponse
+	if err := json.NewDecoder(resp.Body).Decode(&driftResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+
+	return &driftResp, nil
+}
+
 // DestroyDeployment deletes a deployment
 func (c *Client) DestroyDeployment(name string) error {
-	request, err := http.NewRequest("DELETE", fmt.Sprintf("%s/%s", c.BaseURL, name), nil)
+	request, err := http.NewRequest("DELETE", c.endpoint("/"+url.PathEscape(name), nil), nil)
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
 
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	if err := c.authorize(request); err != nil {
+		return err
+	}
 
 	resp, err := c.HTTPClient.Do(request)
 	if err != nil {
@@ -143,8 +329,7 @@ func (c *Client) DestroyDeployment(name string) error {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("destroy request failed with status %d: %s", resp.StatusCode, string(body))
+		return decodeError(resp)
 	}
 
 	return nil
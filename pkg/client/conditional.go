@@ -0,0 +1,134 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"time"
+
+	deskreev1 "github.com/espinozasenior/go-assesstment.git/api/v1"
+)
+
+// Apps carry their resourceVersion, returned by the API as the ETag of the app. The IfMatch
+// helpers send it back so that a write based on a stale read fails with ErrPreconditionFailed
+// instead of overwriting a concurrent change.
+
+const (
+	// modifyAttempts bounds how many times ModifyApp reads and writes an app
+	modifyAttempts = 5
+	// modifyRetryInterval is the wait before the second attempt of ModifyApp, growing linearly
+	modifyRetryInterval = 100 * time.Millisecond
+)
+
+// ETag returns the entity tag of an app, the value of the ETag header the API returns for it
+func ETag(app *deskreev1.AppDeployment) string {
+	return etagOf(app.ResourceVersion)
+}
+
+// etagOf returns the entity tag of the app version resourceVersion
+func etagOf(resourceVersion string) string {
+	return `"` + resourceVersion + `"`
+}
+
+// UpdateAppIfMatch replaces the spec of an AppDeployment with a deploy request, provided the
+// app is still at resourceVersion
+func (c *Client) UpdateAppIfMatch(req DeployRequest, resourceVersion string) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.doIfMatch("PUT", c.appsPath(req.Name), "application/json", etagOf(resourceVersion), req, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// PatchAppIfMatch applies a JSON merge patch to an AppDeployment, provided the app is still at
+// resourceVersion
+func (c *Client) PatchAppIfMatch(name string, patch map[string]interface{}, resourceVersion string) (*deskreev1.AppDeployment, error) {
+	var app deskreev1.AppDeployment
+	if err := c.doIfMatch("PATCH", c.appsPath(name), "application/merge-patch+json", etagOf(resourceVersion), patch, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// DeleteAppIfMatch deletes an AppDeployment, provided the app is still at resourceVersion
+func (c *Client) DeleteAppIfMatch(name, resourceVersion string) error {
+	return c.doIfMatch("DELETE", c.appsPath(name), "", etagOf(resourceVersion), nil, nil)
+}
+
+// IsConflict reports whether a write failed because the app changed since it was read, either
+// against If-Match or in a conflicting update
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrConflict)
+}
+
+// ModifyApp runs a read-modify-write of an app: it reads the app, lets mutate change its deploy
+// request form and writes it back conditionally on the version read. When the app changed in
+// between, the whole cycle is retried on a fresh read, up to a few times; the last conflict is
+// returned if they are all exhausted. An error from mutate aborts without writing.
+func (c *Client) ModifyApp(name string, mutate func(*DeployRequest) error) (*deskreev1.AppDeployment, error) {
+	var err error
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * modifyRetryInterval)
+		}
+
+		var app *deskreev1.AppDeployment
+		if app, err = c.GetApp(name); err != nil {
+			return nil, err
+		}
+
+		req := DeployRequestFromApp(app)
+		if err := mutate(&req); err != nil {
+			return nil, err
+		}
+
+		var updated *deskreev1.AppDeployment
+		if updated, err = c.UpdateAppIfMatch(req, app.ResourceVersion); err == nil {
+			return updated, nil
+		}
+		if !IsConflict(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// DeployRequestFromApp returns the deploy request form of an AppDeployment, the form that
+// UpdateApp and PatchApp write
+func DeployRequestFromApp(app *deskreev1.AppDeployment) DeployRequest {
+	req := DeployRequest{
+		Name:        app.Name,
+		MemoryLimit: app.Spec.MemoryLimit,
+		MinReplicas: app.Spec.MinReplicas,
+		MaxReplicas: app.Spec.MaxReplicas,
+	}
+
+	if len(app.Spec.Template.Spec.Containers) > 0 {
+		container := app.Spec.Template.Spec.Containers[0]
+		req.Image = container.Image
+		for _, source := range container.EnvFrom {
+			switch {
+			case source.ConfigMapRef != nil:
+				req.ConfigMaps = append(req.ConfigMaps, source.ConfigMapRef.Name)
+			case source.SecretRef != nil:
+				req.Secrets = append(req.Secrets, source.SecretRef.Name)
+			}
+		}
+	}
+
+	return req
+}
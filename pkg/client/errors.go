@@ -0,0 +1,142 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors matched by the errors returned for failed requests, e.g.
+// errors.Is(err, client.ErrNotFound)
+var (
+	// ErrNotFound is returned when the requested deployment does not exist
+	ErrNotFound = errors.New("deployment not found")
+	// ErrAlreadyExists is returned when creating a deployment whose name is taken
+	ErrAlreadyExists = errors.New("deployment already exists")
+	// ErrConflict is returned when a deployment was changed concurrently; the request may
+	// succeed if retried
+	ErrConflict = errors.New("conflicting change")
+	// ErrPreconditionFailed is returned when a conditional request finds that the deployment
+	// changed since the version it is based on
+	ErrPreconditionFailed = errors.New("deployment changed since it was read")
+	// ErrInvalid is returned when a request is rejected because of invalid fields
+	ErrInvalid = errors.New("invalid request")
+	// ErrBadRequest is returned when a request is malformed
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized is returned when the token of the client is missing, invalid or expired
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when the caller is not allowed to perform the request
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnavailable is returned when the API or the cluster is temporarily unable to
+	// serve the request
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// codeErrors maps the error codes of the API to the errors they match
+var codeErrors = map[string]error{
+	"NotFound":           ErrNotFound,
+	"AlreadyExists":      ErrAlreadyExists,
+	"Conflict":           ErrConflict,
+	"PreconditionFailed": ErrPreconditionFailed,
+	"Invalid":            ErrInvalid,
+	"BadRequest":         ErrBadRequest,
+	"Unauthorized":       ErrUnauthorized,
+	"Forbidden":          ErrForbidden,
+	"ServiceUnavailable": ErrUnavailable,
+	"TooManyRequests":    ErrUnavailable,
+	"Timeout":            ErrUnavailable,
+}
+
+// statusCodes maps HTTP status codes to error codes, for responses without one
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NotFound",
+	http.StatusMethodNotAllowed:    "MethodNotAllowed",
+	http.StatusConflict:            "Conflict",
+	http.StatusPreconditionFailed:  "PreconditionFailed",
+	http.StatusUnprocessableEntity: "Invalid",
+	http.StatusTooManyRequests:     "TooManyRequests",
+	http.StatusServiceUnavailable:  "ServiceUnavailable",
+	http.StatusGatewayTimeout:      "Timeout",
+}
+
+// FieldError describes why a single field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is the error returned for a request the API answered with an error response
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int `json:"-"`
+	// Code classifies the error, e.g. "NotFound" or "Conflict"
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	// Retryable reports whether the same request may succeed if sent again later
+	Retryable bool `json:"retryable"`
+}
+
+// Error implements error, listing the offending fields after the message
+func (e *APIError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = fmt.Sprintf("request failed with status %d", e.StatusCode)
+	}
+	if len(e.Fields) == 0 {
+		return message
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return fmt.Sprintf("%s (%s)", message, strings.Join(fields, "; "))
+}
+
+// Is matches the error against the Err* errors of its code
+func (e *APIError) Is(target error) bool {
+	matched, ok := codeErrors[e.Code]
+	return ok && matched == target
+}
+
+// decodeError reads the error response of a failed request. Responses that are not in the
+// error format of the API, e.g. from a proxy, are classified by their status code.
+func decodeError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+
+	apiErr := &APIError{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr = &APIError{
+			Message:   strings.TrimSpace(string(body)),
+			Retryable: resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests,
+		}
+	}
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.Code == "" {
+		apiErr.Code = statusCodes[resp.StatusCode]
+	}
+	return apiErr
+}
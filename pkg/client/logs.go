@@ -0,0 +1,122 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogOptions select the log lines StreamLogs returns
+type LogOptions struct {
+	// Follow keeps the stream open for new lines
+	Follow bool
+	// Since only returns lines newer than this, all lines when zero
+	Since time.Duration
+	// Tail only returns this many of the last lines of each container, all lines when negative
+	Tail int64
+	// Container reads a single container of each pod, all of them when empty
+	Container string
+	// Previous reads the logs of the previous, crashed instance of the containers
+	Previous bool
+}
+
+// LogLine represents one line of the logs of an app
+type LogLine struct {
+	Pod       string       `json:"pod"`
+	Container string       `json:"container"`
+	Time      *metav1.Time `json:"time,omitempty"`
+	Line      string       `json:"line,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// StreamLogs passes the log lines of every pod of an app to handle until the logs end, handle
+// returns false or ctx is done
+func (c *Client) StreamLogs(ctx context.Context, name string, options LogOptions, handle func(LogLine) bool) error {
+	query := url.Values{}
+	if options.Follow {
+		query.Set("follow", "true")
+	}
+	if options.Since > 0 {
+		query.Set("since", options.Since.String())
+	}
+	if options.Tail >= 0 {
+		query.Set("tail", strconv.FormatInt(options.Tail, 10))
+	}
+	if options.Container != "" {
+		query.Set("container", options.Container)
+	}
+	if options.Previous {
+		query.Set("previous", "true")
+	}
+
+	endpoint := c.appsPath(name) + "/logs"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	if err := c.authorize(request); err != nil {
+		return err
+	}
+
+	// The stream outlives any request timeout of the regular client
+	streamClient := *c.HTTPClient
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var line LogLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("error decoding log line: %v", err)
+		}
+		if !handle(line) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("error reading logs: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Watch event types
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// watchRetryInterval is how long WatchApp waits before resuming a dropped stream
+const watchRetryInterval = time.Second
+
+// WatchEvent represents a status change of an app streamed by the watch endpoint
+type WatchEvent struct {
+	Type               string             `json:"type"`
+	Name               string             `json:"name"`
+	Namespace          string             `json:"namespace"`
+	ResourceVersion    string             `json:"resourceVersion"`
+	Status             string             `json:"status"`
+	Replicas           int32              `json:"replicas"`
+	DesiredReplicas    int32              `json:"desiredReplicas"`
+	Generation         int64              `json:"generation"`
+	ObservedGeneration int64              `json:"observedGeneration"`
+	Message            string             `json:"message,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// WatchApp streams the status changes of an app to handle, starting with its current state,
+// until handle returns false, the app is deleted or ctx is done. A dropped stream is resumed
+// from the last resourceVersion seen.
+func (c *Client) WatchApp(ctx context.Context, name string, handle func(WatchEvent) bool) error {
+	// The stream outlives any request timeout of the regular client
+	streamClient := *c.HTTPClient
+	streamClient.Timeout = 0
+
+	resourceVersion := ""
+	for {
+		done, err := c.streamWatch(ctx, &streamClient, name, &resourceVersion, handle)
+		if done || ctx.Err() != nil {
+			return err
+		}
+		if err != nil && resourceVersion == "" {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// streamWatch reads one watch stream, reporting done when the watch is over rather than dropped
+func (c *Client) streamWatch(ctx context.Context, httpClient *http.Client, name string, resourceVersion *string,
+	handle func(WatchEvent) bool) (bool, error) {
+	endpoint := c.appsPath(name) + "/watch"
+	if *resourceVersion != "" {
+		endpoint += "?" + url.Values{"resourceVersion": {*resourceVersion}}.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return true, fmt.Errorf("error creating request: %v", err)
+	}
+	request.Header.Set("Accept", "text/event-stream")
+	if err := c.authorize(request); err != nil {
+		return true, err
+	}
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("error sending request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		// Transient errors drop the stream so that it is resumed, the rest end the watch
+		apiErr := decodeError(resp)
+		return !apiErr.Retryable, apiErr
+	}
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event WatchEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return true, fmt.Errorf("error decoding watch event: %v", err)
+			}
+			data.Reset()
+
+			*resourceVersion = event.ResourceVersion
+			if !handle(event) || event.Type == WatchEventDeleted {
+				return true, nil
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return false, scanner.Err()
+}
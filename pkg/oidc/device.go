@@ -0,0 +1,245 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deviceCodeGrantType is the grant type of the device authorization grant (RFC 8628)
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultPollInterval is used when the provider does not say how often to poll
+const defaultPollInterval = 5 * time.Second
+
+// DeviceAuthorization is the provider response starting a device flow
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+
+	// codeVerifier is the PKCE secret sent when redeeming the device code
+	codeVerifier string
+}
+
+// Tokens are the tokens the provider hands out at the end of a flow
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+}
+
+// tokenError is the OAuth 2.0 error response of the token endpoint
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// DeviceFlow runs the device authorization grant against a provider
+type DeviceFlow struct {
+	Provider   *Provider
+	ClientID   string
+	Scopes     []string
+	HTTPClient *http.Client
+}
+
+// Start asks the provider for a device and user code. The user then opens the verification
+// URI and enters the user code while Wait polls for the outcome.
+func (d *DeviceFlow) Start(ctx context.Context) (*DeviceAuthorization, error) {
+	if d.Provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider %s does not support the device authorization grant", d.Provider.Issuer)
+	}
+
+	codeVerifier, err := newCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := d.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile", "offline_access"}
+	}
+
+	form := url.Values{
+		"client_id":             {d.ClientID},
+		"scope":                 {strings.Join(scopes, " ")},
+		"code_challenge":        {codeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	var authorization DeviceAuthorization
+	if err := d.postForm(ctx, d.Provider.DeviceAuthorizationEndpoint, form, &authorization); err != nil {
+		return nil, fmt.Errorf("error starting device authorization: %v", err)
+	}
+	authorization.codeVerifier = codeVerifier
+
+	return &authorization, nil
+}
+
+// Wait polls the token endpoint until the user approves or denies the request, or the
+// device code expires
+func (d *DeviceFlow) Wait(ctx context.Context, authorization *DeviceAuthorization) (*Tokens, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	if authorization.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	form := url.Values{
+		"grant_type":    {deviceCodeGrantType},
+		"device_code":   {authorization.DeviceCode},
+		"client_id":     {d.ClientID},
+		"code_verifier": {authorization.codeVerifier},
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("device authorization expired before it was approved")
+		case <-time.After(interval):
+		}
+
+		var tokens Tokens
+		err := d.postForm(ctx, d.Provider.TokenEndpoint, form, &tokens)
+		if err == nil {
+			if tokens.IDToken == "" {
+				return nil, fmt.Errorf("OIDC provider returned no ID token, is the openid scope allowed?")
+			}
+			return &tokens, nil
+		}
+
+		oauthErr, ok := err.(*tokenError)
+		if !ok {
+			return nil, fmt.Errorf("error polling for tokens: %v", err)
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, fmt.Errorf("login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("device authorization expired before it was approved")
+		default:
+			return nil, fmt.Errorf("error polling for tokens: %v", oauthErr)
+		}
+	}
+}
+
+// Refresh exchanges a provider refresh token for new tokens
+func Refresh(ctx context.Context, httpClient *http.Client, provider *Provider, clientID, refreshToken string) (*Tokens, error) {
+	d := &DeviceFlow{Provider: provider, ClientID: clientID, HTTPClient: httpClient}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientID},
+	}
+
+	var tokens Tokens
+	if err := d.postForm(ctx, provider.TokenEndpoint, form, &tokens); err != nil {
+		return nil, fmt.Errorf("error refreshing tokens: %v", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("OIDC provider returned no ID token on refresh")
+	}
+	if tokens.RefreshToken == "" {
+		// Providers that do not rotate refresh tokens keep the old one valid
+		tokens.RefreshToken = refreshToken
+	}
+	return &tokens, nil
+}
+
+// postForm posts a form to a provider endpoint, decoding a 200 response into out and an
+// OAuth 2.0 error response into a *tokenError
+func (d *DeviceFlow) postForm(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr tokenError
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// newCodeVerifier returns a random PKCE code verifier (RFC 7636)
+func newCodeVerifier() (string, error) {
+	verifier := make([]byte, 32)
+	if _, err := rand.Read(verifier); err != nil {
+		return "", fmt.Errorf("error generating PKCE verifier: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(verifier), nil
+}
+
+// codeChallenge returns the S256 PKCE challenge of a code verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oidc implements the parts of OpenID Connect the platform needs: provider
+// discovery, the OAuth 2.0 device authorization grant with PKCE for the CLI, and ID token
+// verification against the provider JWKS for the API server.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider is the subset of the OpenID provider metadata used by this package
+type Provider struct {
+	Issuer                      string `json:"issuer"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	JWKSURI                     string `json:"jwks_uri"`
+}
+
+// defaultHTTPClient is used when no client is given
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Discover fetches the OpenID provider metadata of issuer
+func Discover(ctx context.Context, httpClient *http.Client, issuer string) (*Provider, error) {
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	request, err := http.NewRequestWithContext(ctx, "GET", wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery request: %v", err)
+	}
+
+	var provider Provider
+	if err := doJSON(httpClient, request, &provider); err != nil {
+		return nil, fmt.Errorf("error discovering OIDC provider %s: %v", issuer, err)
+	}
+
+	// The issuer must match exactly, otherwise tokens would be checked against the wrong "iss"
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("OIDC provider issuer %q does not match %q", provider.Issuer, issuer)
+	}
+	if provider.JWKSURI == "" || provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider %s metadata lacks jwks_uri or token_endpoint", issuer)
+	}
+
+	return &provider, nil
+}
+
+// doJSON sends request and decodes a 200 JSON response into out
+func doJSON(httpClient *http.Client, request *http.Request, out interface{}) error {
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2025 LuisEspinoza.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is the leeway allowed when checking token timestamps
+const clockSkew = time.Minute
+
+// jwksRefreshInterval bounds how often an unknown key ID triggers a JWKS refetch
+const jwksRefreshInterval = time.Minute
+
+// ErrTokenExpired is returned for ID tokens past their expiry
+var ErrTokenExpired = errors.New("ID token expired")
+
+// IDToken is a verified ID token
+type IDToken struct {
+	Subject string
+	Expiry  time.Time
+	claims  map[string]interface{}
+}
+
+// StringClaim returns a string claim of the token, or "" when absent
+func (t *IDToken) StringClaim(name string) string {
+	value, _ := t.claims[name].(string)
+	return value
+}
+
+// BoolClaim returns a boolean claim of the token, or false when absent
+func (t *IDToken) BoolClaim(name string) bool {
+	value, _ := t.claims[name].(bool)
+	return value
+}
+
+// StringsClaim returns a claim holding a list of strings, or a single string, as a slice
+func (t *IDToken) StringsClaim(name string) []string {
+	switch value := t.claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Verifier checks ID tokens issued by a provider for a client. Signing keys are fetched from
+// the provider JWKS and cached; a token signed with an unknown key refetches them, so key
+// rotation is picked up without a restart.
+type Verifier struct {
+	Provider   *Provider
+	ClientID   string
+	HTTPClient *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+	// fetchedAt is the time of the last JWKS fetch, failed or not, and fetchErr its error
+	fetchedAt time.Time
+	fetchErr  error
+	// fetching is closed when the fetch in flight completes, nil when there is none
+	fetching chan struct{}
+}
+
+// NewVerifier returns a verifier for ID tokens issued by provider to clientID
+func NewVerifier(provider *Provider, clientID string, httpClient *http.Client) *Verifier {
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	return &Verifier{Provider: provider, ClientID: clientID, HTTPClient: httpClient}
+}
+
+// Verify checks the RS256 signature, issuer, audience and lifetime of an ID token
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (*IDToken, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %v", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Algorithm)
+	}
+
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid ID token signature")
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %v", err)
+	}
+	token := &IDToken{claims: claims}
+	token.Subject = token.StringClaim("sub")
+
+	if issuer := token.StringClaim("iss"); issuer != v.Provider.Issuer {
+		return nil, fmt.Errorf("ID token issued by %q, expected %q", issuer, v.Provider.Issuer)
+	}
+	if !containsString(token.StringsClaim("aud"), v.ClientID) {
+		return nil, fmt.Errorf("ID token not issued for client %q", v.ClientID)
+	}
+	if token.Subject == "" {
+		return nil, fmt.Errorf("ID token has no subject")
+	}
+
+	now := time.Now()
+	expiry, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("ID token has no expiry")
+	}
+	token.Expiry = time.Unix(int64(expiry), 0)
+	if now.After(token.Expiry.Add(clockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, fmt.Errorf("ID token not valid yet")
+	}
+
+	return token, nil
+}
+
+// UnverifiedExpiry reads the expiry of a token without checking it, for clients deciding
+// when to refresh a token they received directly from the provider
+func UnverifiedExpiry(rawToken string) (time.Time, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed token")
+	}
+
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return time.Time{}, fmt.Errorf("malformed token claims: %v", err)
+	}
+	return time.Unix(claims.Expiry, 0), nil
+}
+
+// key returns the signing key with the given ID, refetching the JWKS when it is unknown.
+// Concurrent callers share a single fetch, and fetches, failed ones included, happen at most
+// once per jwksRefreshInterval.
+func (v *Verifier) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	if key := v.lookup(keyID); key != nil {
+		v.mu.Unlock()
+		return key, nil
+	}
+
+	fetching := v.fetching
+	if fetching == nil {
+		if !v.fetchedAt.IsZero() && time.Since(v.fetchedAt) < jwksRefreshInterval {
+			err := v.fetchErr
+			v.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("ID token signed with unknown key %q", keyID)
+		}
+		fetching = make(chan struct{})
+		v.fetching = fetching
+		v.fetchedAt = time.Now()
+		// The fetch serves every waiting caller, so it does not end with the request that started it
+		go v.fetch(context.WithoutCancel(ctx), fetching)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key := v.lookup(keyID); key != nil {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, v.fetchErr
+	}
+	return nil, fmt.Errorf("ID token signed with unknown key %q", keyID)
+}
+
+// fetch refreshes the cached keys from the JWKS and closes done once they are stored
+func (v *Verifier) fetch(ctx context.Context, done chan struct{}) {
+	keys, err := fetchKeys(ctx, v.HTTPClient, v.Provider.JWKSURI)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err == nil {
+		v.keys = keys
+	}
+	v.fetchErr = err
+	v.fetching = nil
+	close(done)
+}
+
+// lookup finds a cached key; tokens without a key ID match when the JWKS holds a single key
+func (v *Verifier) lookup(keyID string) *rsa.PublicKey {
+	if keyID == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+	return v.keys[keyID]
+}
+
+// fetchKeys downloads the RSA signing keys of a JWKS
+func fetchKeys(ctx context.Context, httpClient *http.Client, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating JWKS request: %v", err)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := doJSON(httpClient, request, &jwks); err != nil {
+		return nil, fmt.Errorf("error fetching JWKS: %v", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}